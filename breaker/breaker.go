@@ -0,0 +1,135 @@
+package breaker
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	Closed   State = "closed"
+	Open     State = "open"
+	HalfOpen State = "half-open"
+)
+
+// Breaker opens after Threshold consecutive failures, rejects calls for
+// Cooldown and then lets a single probe through: a successful probe closes
+// it again, a failed one re-opens it.
+type Breaker struct {
+	Name      string
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	trips    int
+}
+
+func New(name string, threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		Name:      name,
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		state:     Closed,
+	}
+}
+
+// Allow reports whether a call may go through. When it may not, it returns
+// how long to wait before asking again.
+func (b *Breaker) Allow() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		return 0, true
+	case Open:
+		if wait := time.Until(b.openedAt.Add(b.Cooldown)); wait > 0 {
+			return wait, false
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+		return 0, true
+	default:
+		if !b.probing {
+			b.probing = true
+			return 0, true
+		}
+		return b.probeWait(), false
+	}
+}
+
+// Success records a call that reached the provider and got an answer.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != Closed {
+		b.setState(Closed)
+	}
+}
+
+// Failure records a call that failed because of the provider.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures += 1
+	b.probing = false
+	if b.state == HalfOpen || (b.state == Closed && b.failures >= b.Threshold) {
+		b.openedAt = time.Now()
+		b.trips += 1
+		b.setState(Open)
+	}
+}
+
+// Release gives up a call that ended without telling anything about the
+// provider, such as a cancelled one.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Trips returns how many times the breaker has opened.
+func (b *Breaker) Trips() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.trips
+}
+
+func (b *Breaker) setState(state State) {
+	switch state {
+	case Open:
+		log.Printf("Circuit breaker %s: %s -> open after %d consecutive failures, next probe in %s\n", b.Name, b.state, b.failures, b.Cooldown)
+	default:
+		log.Printf("Circuit breaker %s: %s -> %s\n", b.Name, b.state, state)
+	}
+	b.state = state
+}
+
+func (b *Breaker) probeWait() time.Duration {
+	if b.Cooldown < time.Second {
+		return b.Cooldown
+	}
+	return time.Second
+}
@@ -0,0 +1,111 @@
+package breaker
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+type step int
+
+const (
+	allowed step = iota
+	denied
+	success
+	failure
+	release
+	cool // waits out the cooldown
+)
+
+const cooldown = 10 * time.Millisecond
+
+func TestBreaker(t *testing.T) {
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		steps    []step
+		state    State
+		trips    int
+	}{
+		{"closed allows", time.Hour, []step{allowed, allowed, allowed}, Closed, 0},
+		{"below threshold", time.Hour, []step{failure, failure, allowed}, Closed, 0},
+		{"success resets failures", time.Hour, []step{failure, failure, success, failure, failure, allowed}, Closed, 0},
+		{"opens at threshold", time.Hour, []step{failure, failure, failure, denied}, Open, 1},
+		{"open until cooldown", cooldown, []step{failure, failure, failure, denied, cool, allowed}, HalfOpen, 1},
+		{"single probe", cooldown, []step{failure, failure, failure, cool, allowed, denied}, HalfOpen, 1},
+		{"probe success closes", cooldown, []step{failure, failure, failure, cool, allowed, success, allowed, allowed}, Closed, 1},
+		{"probe failure reopens", cooldown, []step{failure, failure, failure, cool, allowed, failure, denied}, Open, 2},
+		{"released probe lets another through", cooldown, []step{failure, failure, failure, cool, allowed, release, allowed}, HalfOpen, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("test", 3, tt.cooldown)
+			for i, s := range tt.steps {
+				switch s {
+				case allowed, denied:
+					wait, ok := b.Allow()
+					if ok != (s == allowed) {
+						t.Fatalf("step %d: Allow() = %v in state %s", i, ok, b.State())
+					}
+					if !ok && wait <= 0 {
+						t.Fatalf("step %d: Allow() denied without a wait", i)
+					}
+				case success:
+					b.Success()
+				case failure:
+					b.Failure()
+				case release:
+					b.Release()
+				case cool:
+					time.Sleep(tt.cooldown)
+				}
+			}
+			if b.State() != tt.state || b.Trips() != tt.trips {
+				t.Errorf("state %s with %d trips, want %s with %d", b.State(), b.Trips(), tt.state, tt.trips)
+			}
+		})
+	}
+}
+
+func TestNewThreshold(t *testing.T) {
+	b := New("test", 0, time.Hour)
+	b.Failure()
+	if b.State() != Open {
+		t.Errorf("state %s after a failure with threshold 0, want %s", b.State(), Open)
+	}
+}
+
+func TestProviderDown(t *testing.T) {
+	status := func(code int) error {
+		return &models.HTTPError{StatusCode: code, Status: http.StatusText(code)}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"empty result", models.ErrEmptyResult, false},
+		{"network", &url.Error{Op: "Get", URL: "http://localhost", Err: errors.New("connection refused")}, true},
+		{"rate limited", status(http.StatusTooManyRequests), true},
+		{"server error", status(http.StatusInternalServerError), true},
+		{"bad gateway", status(http.StatusBadGateway), true},
+		{"unauthorized", status(http.StatusUnauthorized), true},
+		{"payment required", status(http.StatusPaymentRequired), true},
+		{"forbidden", status(http.StatusForbidden), true},
+		{"bad request", status(http.StatusBadRequest), false},
+		{"not found", status(http.StatusNotFound), false},
+		{"gone", status(http.StatusGone), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := providerDown(tt.err); got != tt.want {
+				t.Errorf("providerDown(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
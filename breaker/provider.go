@@ -0,0 +1,195 @@
+package breaker
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/logfile"
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Provider guards another provider with a Breaker. While the breaker is open
+// lookups go to Fallback when it is set, otherwise they pause until the
+// breaker lets a probe through.
+type Provider struct {
+	interfaces.Provider
+	Breaker  *Breaker
+	Fallback interfaces.Provider
+}
+
+var (
+	_ interfaces.Provider        = (*Provider)(nil)
+	_ interfaces.BatchProvider   = (*Provider)(nil)
+	_ interfaces.BreakerReporter = (*Provider)(nil)
+)
+
+func Wrap(provider interfaces.Provider, threshold int, cooldown time.Duration) *Provider {
+	return &Provider{
+		Provider: provider,
+		Breaker:  New(provider.ProviderName(), threshold, cooldown),
+	}
+}
+
+func (p *Provider) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	useFallback, err := p.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if useFallback {
+		return p.Fallback.Lookup(ctx, model)
+	}
+
+	result, err := p.Provider.Lookup(ctx, model)
+	p.record(ctx, err)
+	return result, err
+}
+
+// BatchSize returns the batch size of the guarded provider, 1 when it can't
+// look up batches.
+func (p *Provider) BatchSize() int {
+	if b, ok := p.Provider.(interfaces.BatchProvider); ok {
+		return b.BatchSize()
+	}
+	return 1
+}
+
+// LookupBatch passes the records of a batch which are linked to an OSM
+// object to the guarded provider as a single request for the breaker. The
+// others are searched one by one through Lookup, as are all records when
+// the provider can't look up batches or the fallback is used.
+func (p *Provider) LookupBatch(ctx context.Context, batch []models.Model) ([]*models.Result, []error) {
+	b, ok := p.Provider.(interfaces.BatchProvider)
+	if !ok {
+		return lookupEach(ctx, p, batch)
+	}
+
+	results := make([]*models.Result, len(batch))
+	errs := make([]error, len(batch))
+	var (
+		linked  []models.Model
+		indexes []int
+	)
+	for i, model := range batch {
+		if models.Linked(model) == nil {
+			results[i], errs[i] = p.Lookup(ctx, model)
+			continue
+		}
+		linked = append(linked, model)
+		indexes = append(indexes, i)
+	}
+	if len(linked) == 0 {
+		return results, errs
+	}
+
+	var (
+		linkedResults []*models.Result
+		linkedErrs    []error
+	)
+	useFallback, err := p.allow(ctx)
+	switch {
+	case err != nil:
+		linkedResults = make([]*models.Result, len(linked))
+		linkedErrs = make([]error, len(linked))
+		for i := range linkedErrs {
+			linkedErrs[i] = err
+		}
+	case useFallback:
+		linkedResults, linkedErrs = lookupEach(ctx, p.Fallback, linked)
+	default:
+		linkedResults, linkedErrs = b.LookupBatch(ctx, linked)
+		var down error
+		for _, err := range linkedErrs {
+			if providerDown(err) {
+				down = err
+				break
+			}
+		}
+		p.record(ctx, down)
+	}
+	for j, i := range indexes {
+		results[i], errs[i] = linkedResults[j], linkedErrs[j]
+	}
+	return results, errs
+}
+
+// allow waits until the breaker lets a request through, or reports that the
+// fallback has to be used while it is open.
+func (p *Provider) allow(ctx context.Context) (bool, error) {
+	for {
+		wait, ok := p.Breaker.Allow()
+		if ok {
+			return false, nil
+		}
+		if p.Fallback != nil {
+			return true, nil
+		}
+
+		log.Printf("Circuit breaker %s is open, pausing for %s\n", p.Breaker.Name, wait.Round(time.Millisecond))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// record reports the outcome of an allowed request to the breaker.
+func (p *Provider) record(ctx context.Context, err error) {
+	switch {
+	case ctx.Err() != nil:
+		p.Breaker.Release()
+	case providerDown(err):
+		p.Breaker.Failure()
+	default:
+		p.Breaker.Success()
+	}
+}
+
+func lookupEach(ctx context.Context, provider interfaces.Provider, batch []models.Model) ([]*models.Result, []error) {
+	results := make([]*models.Result, len(batch))
+	errs := make([]error, len(batch))
+	for i, model := range batch {
+		results[i], errs[i] = provider.Lookup(ctx, model)
+	}
+	return results, errs
+}
+
+func (p *Provider) BreakerStates() []models.BreakerState {
+	states := []models.BreakerState{{
+		Provider: p.Breaker.Name,
+		State:    string(p.Breaker.State()),
+		Trips:    p.Breaker.Trips(),
+	}}
+	if r, ok := p.Fallback.(interfaces.BreakerReporter); ok {
+		states = append(states, r.BreakerStates()...)
+	}
+	return states
+}
+
+// providerDown tells failures of the provider itself apart from record
+// level ones such as an empty result or a 4xx for a single query. Of the
+// HTTP statuses only 5xx and those refusing the account (401, 402, 403)
+// count.
+func providerDown(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch category, status := logfile.Classify(err); category {
+	case logfile.CategoryNetwork, logfile.CategoryRateLimited:
+		return true
+	case logfile.CategoryHTTP:
+		switch {
+		case status >= http.StatusInternalServerError,
+			status == http.StatusUnauthorized,
+			status == http.StatusPaymentRequired,
+			status == http.StatusForbidden:
+			return true
+		}
+	}
+	return false
+}
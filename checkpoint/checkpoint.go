@@ -0,0 +1,81 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps the last processed record ID per provider and entity in a JSON
+// file, so an interrupted run can be resumed.
+type Store struct {
+	mu       sync.Mutex
+	fileName string
+	points   map[string]map[string]int
+}
+
+// Open loads checkpoints from fileName. A missing file is an empty store.
+func Open(fileName string) (*Store, error) {
+	s := &Store{
+		fileName: fileName,
+		points:   map[string]map[string]int{},
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.points); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Last returns the last processed ID, zero when nothing was processed yet.
+func (s *Store) Last(provider, entity string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.points[provider][entity]
+}
+
+// Save records id as the last processed one and flushes the store to disk.
+func (s *Store) Save(provider, entity string, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.points[provider] == nil {
+		s.points[provider] = map[string]int{}
+	}
+	s.points[provider][entity] = id
+	return s.write()
+}
+
+func (s *Store) write() error {
+	data, err := json.MarshalIndent(s.points, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.fileName), filepath.Base(s.fileName)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.fileName)
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lensgolda/geocapture/checkpoint"
+	"github.com/lensgolda/geocapture/export"
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/localize"
+	"github.com/lensgolda/geocapture/logfile"
+	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/providers/chain"
+	"github.com/lensgolda/geocapture/providers/consensus"
+	"github.com/lensgolda/geocapture/settings"
+	"github.com/lensgolda/geocapture/sink"
+)
+
+const (
+	entityCountries = "countries"
+	entityCities    = "cities"
+)
+
+// runFlags are shared by the commands that drive providers.
+type runFlags struct {
+	providers string
+	fallback  string
+	chain     bool
+	consensus bool
+	locales   string
+	allNames  bool
+	reverse   bool
+	limit     int
+	fromID    int
+	toID      int
+	workers   int
+	resume    bool
+	refresh   bool
+	dryRun    bool
+}
+
+func (f *runFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.providers, "provider", "", "comma separated providers to run, overrides GEOCAPTURE_PROVIDER")
+	fs.StringVar(&f.fallback, "fallback", "", "provider to use while a provider's circuit breaker is open, pause otherwise")
+	fs.BoolVar(&f.chain, "chain", false, "try the providers in order for every record instead of running them one after another")
+	fs.BoolVar(&f.consensus, "consensus", false, "ask all providers about every record and store the names they agree on")
+	fs.StringVar(&f.locales, "locales", "", "comma separated BCP 47 locales to store, overrides GEOCAPTURE_LOCALES")
+	fs.BoolVar(&f.allNames, "all-names", false, "store every alternate name as a ranked alternative, overrides GEOCAPTURE_ALL_NAMES")
+	fs.BoolVar(&f.reverse, "reverse", false, "resolve cities with coordinates by reverse geocoding first, overrides GEOCAPTURE_REVERSE")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "print results as JSON lines instead of writing them to the database")
+}
+
+// selected builds the providers to run, combined into a single chain or
+// consensus provider when -chain or -consensus is set.
+func (f *runFlags) selected() ([]interfaces.Provider, error) {
+	if f.chain && f.consensus {
+		return nil, errors.New("-chain and -consensus can't be used together")
+	}
+	if f.allNames {
+		settings.Config.App.AllNames = true
+	}
+	if f.reverse {
+		settings.Config.App.Reverse = true
+	}
+	if f.locales != "" {
+		// providers asking for names per language default to these
+		list, err := f.localeList()
+		if err != nil {
+			return nil, err
+		}
+		settings.Config.App.Locales = list
+	}
+
+	selected, err := selectedProviders(f.providers, f.fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case f.chain:
+		c := chain.New(selected...)
+		if c.Locales, err = f.localeList(); err != nil {
+			return nil, err
+		}
+		return []interfaces.Provider{c}, nil
+	case f.consensus:
+		return []interfaces.Provider{consensus.New(selected...)}, nil
+	}
+	return selected, nil
+}
+
+func (f *runFlags) registerRange(fs *flag.FlagSet) {
+	fs.IntVar(&f.limit, "limit", 0, "maximum number of records to process, 0 means no limit")
+	fs.IntVar(&f.fromID, "from-id", 0, "process records with id >= from-id")
+	fs.IntVar(&f.toID, "to-id", 0, "process records with id <= to-id")
+	fs.IntVar(&f.workers, "workers", 0, "number of concurrent lookups per provider, overrides GEOCAPTURE_WORKERS")
+	fs.BoolVar(&f.resume, "resume", false, "continue after the last checkpointed record of each provider")
+	fs.BoolVar(&f.refresh, "refresh", false, "only process records linked to an OSM object, looking them up by ID")
+}
+
+// localeList returns the locales from -locales or GEOCAPTURE_LOCALES.
+func (f *runFlags) localeList() ([]string, error) {
+	list := settings.Config.App.Locales
+	if f.locales != "" {
+		list = splitList(f.locales)
+	}
+	return locales.Parse(list)
+}
+
+func (f *runFlags) options() (localize.Options, error) {
+	if f.workers == 0 {
+		f.workers = settings.Config.App.Workers
+	}
+	list, err := f.localeList()
+	if err != nil {
+		return localize.Options{}, err
+	}
+	return localize.Options{
+		Limit:   f.limit,
+		FromID:  f.fromID,
+		ToID:    f.toID,
+		Locales: list,
+		Workers: f.workers,
+		Resume:  f.resume,
+		Refresh: f.refresh,
+	}, nil
+}
+
+// entityArg takes the leading countries|cities argument so flags may follow it.
+func entityArg(args []string) (string, []string, error) {
+	if len(args) == 0 || (args[0] != entityCountries && args[0] != entityCities) {
+		return "", nil, errors.New("expected countries or cities as the first argument")
+	}
+	return args[0], args[1:], nil
+}
+
+func runLocalize(args []string) error {
+	entity, args, err := entityArg(args)
+	if err != nil {
+		return err
+	}
+
+	var rf runFlags
+	fs := flag.NewFlagSet("localize "+entity, flag.ExitOnError)
+	rf.register(fs)
+	rf.registerRange(fs)
+	_ = fs.Parse(args)
+
+	if err := loadSettings(); err != nil {
+		return err
+	}
+	selected, err := rf.selected()
+	if err != nil {
+		return err
+	}
+	opts, err := rf.options()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	db, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var (
+		store       interfaces.Sink = sink.NewPostgres(db)
+		checkpoints *checkpoint.Store
+	)
+	if rf.dryRun {
+		store = sink.NewWriter(os.Stdout)
+	} else {
+		checkpoints, err = checkpoint.Open(settings.Config.App.CheckpointFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, provider := range selected {
+		runner := localize.NewRunner(db, provider, store)
+		runner.Options = opts
+		runner.Checkpoints = checkpoints
+
+		var summary *models.Summary
+		if entity == entityCountries {
+			summary, err = runner.Countries(ctx)
+		} else {
+			summary, err = runner.Cities(ctx)
+		}
+		fmt.Println(summary)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println("Success...OK")
+	return nil
+}
+
+func runRetryFailed(args []string) error {
+	entity, args, err := entityArg(args)
+	if err != nil {
+		return err
+	}
+
+	var (
+		rf         runFlags
+		file       string
+		categories string
+		legacy     bool
+	)
+	fs := flag.NewFlagSet("retry-failed "+entity, flag.ExitOnError)
+	rf.register(fs)
+	fs.StringVar(&file, "file", "", "failed file to read, the provider's own .failed file by default")
+	fs.StringVar(&categories, "category", "", "comma separated failure categories to retry, all by default")
+	fs.BoolVar(&legacy, "legacy-ids", false, "also retry bare IDs from failure logs of older versions as records of the given entity")
+	_ = fs.Parse(args)
+
+	var retryCategories []logfile.Category
+	for _, name := range splitList(categories) {
+		c, err := logfile.ParseCategory(name)
+		if err != nil {
+			return err
+		}
+		retryCategories = append(retryCategories, c)
+	}
+
+	if err := loadSettings(); err != nil {
+		return err
+	}
+	selected, err := rf.selected()
+	if err != nil {
+		return err
+	}
+	opts, err := rf.options()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	db, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var store interfaces.Sink = sink.NewPostgres(db)
+	if rf.dryRun {
+		store = sink.NewWriter(os.Stdout)
+	}
+
+	for _, provider := range selected {
+		fileName := file
+		if fileName == "" {
+			fileName = provider.FailedFile()
+		}
+
+		runner := localize.NewRunner(db, provider, store)
+		runner.Options = opts
+		failed := logfile.NewFailed(provider, runner)
+		failed.Categories = retryCategories
+		failed.Legacy = legacy
+		failed.DryRun = rf.dryRun
+
+		var summary *models.Summary
+		if entity == entityCountries {
+			summary, err = failed.ProcessFailedCountries(ctx, db, fileName)
+		} else {
+			summary, err = failed.ProcessFailedCities(ctx, db, fileName)
+		}
+		fmt.Println(summary)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println("Success...OK")
+	return nil
+}
+
+func runProviders(args []string) error {
+	fs := flag.NewFlagSet("providers", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	for _, r := range providers.All() {
+		fmt.Printf("%s\n", r.Name)
+		for _, key := range r.Settings {
+			status := "set"
+			if os.Getenv(key) == "" {
+				status = "missing"
+			}
+			fmt.Printf("    %s (%s)\n", key, status)
+		}
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	entity, args, err := entityArg(args)
+	if err != nil {
+		return err
+	}
+
+	var (
+		localeList string
+		opts       export.Options
+		out        string
+	)
+	fs := flag.NewFlagSet("export "+entity, flag.ExitOnError)
+	fs.StringVar(&localeList, "locales", "", "comma separated locales to export, all by default")
+	fs.StringVar(&opts.Format, "format", export.FormatCSV, "output format: csv or json")
+	fs.Float64Var(&opts.MaxConfidence, "max-confidence", 0, "only export consensus translations with a confidence below this value")
+	fs.StringVar(&out, "out", "", "output file, stdout by default")
+	_ = fs.Parse(args)
+
+	if err := loadSettings(); err != nil {
+		return err
+	}
+	if opts.Locales, err = locales.Parse(splitList(localeList)); err != nil {
+		return err
+	}
+
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	w := os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		w = f
+	}
+
+	db, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	count, err := export.Translations(ctx, db, entity, opts, w)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d %s translations\n", count, entity)
+	return nil
+}
+
+func runCheckConfig(args []string) error {
+	var names string
+	fs := flag.NewFlagSet("check-config", flag.ExitOnError)
+	fs.StringVar(&names, "provider", "", "comma separated providers to check, overrides GEOCAPTURE_PROVIDER")
+	_ = fs.Parse(args)
+
+	if err := loadSettings(); err != nil {
+		return err
+	}
+
+	fmt.Print("Checking locales...")
+	list, err := locales.Parse(settings.Config.App.Locales)
+	if err != nil {
+		fmt.Println("ERROR")
+		return err
+	}
+	fmt.Printf(" %s OK\n", strings.Join(list, ","))
+
+	fmt.Print("Checking providers...")
+	selected, err := selectedProviders(names, "")
+	if err != nil {
+		fmt.Println("ERROR")
+		return err
+	}
+	for _, provider := range selected {
+		fmt.Printf(" %s", provider.ProviderName())
+	}
+	fmt.Printf(" OK\n")
+
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	db, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
@@ -0,0 +1,134 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Translation is a single row of cities_translations or
+// countries_translations_temp.
+type Translation struct {
+	ID         int      `json:"id"`
+	Locale     string   `json:"locale"`
+	Name       string   `json:"name"`
+	IntName    *string  `json:"int_name,omitempty"`
+	Provider   *string  `json:"provider,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+var queries = map[string]string{
+	"cities":    "SELECT city_id, locale, name, int_name, provider, confidence FROM cities_translations",
+	"countries": "SELECT country_id, locale, name, int_name, provider, confidence FROM countries_translations_temp",
+}
+
+// Options restrict what Translations exports.
+type Options struct {
+	Format  string
+	Locales []string
+	// MaxConfidence, when positive, keeps only translations chosen by
+	// consensus with a confidence below it, i.e. the disagreements.
+	MaxConfidence float64
+}
+
+// Translations writes stored translations of the given entity to w in csv
+// or json (JSON lines) format.
+func Translations(ctx context.Context, db *sql.DB, entity string, opts Options, w io.Writer) (int, error) {
+	query, ok := queries[entity]
+	if !ok {
+		return 0, fmt.Errorf("unknown entity %q", entity)
+	}
+
+	var (
+		args       []interface{}
+		conditions []string
+	)
+	if len(opts.Locales) != 0 {
+		placeholders := make([]string, len(opts.Locales))
+		for i, locale := range opts.Locales {
+			args = append(args, locale)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, "locale IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if opts.MaxConfidence > 0 {
+		args = append(args, opts.MaxConfidence)
+		conditions = append(conditions, fmt.Sprintf("confidence < $%d", len(args)))
+	}
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY 1, 2"
+
+	var write func(t Translation) error
+	var flush func() error
+	switch opts.Format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"id", "locale", "name", "int_name", "provider", "confidence"}); err != nil {
+			return 0, err
+		}
+		write = func(t Translation) error {
+			confidence := ""
+			if t.Confidence != nil {
+				confidence = strconv.FormatFloat(*t.Confidence, 'f', 2, 64)
+			}
+			return cw.Write([]string{strconv.Itoa(t.ID), t.Locale, t.Name, deref(t.IntName), deref(t.Provider), confidence})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		write = func(t Translation) error {
+			return enc.Encode(t)
+		}
+		flush = func() error {
+			return nil
+		}
+	default:
+		return 0, fmt.Errorf("unknown format %q", opts.Format)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var count int
+	for rows.Next() {
+		var t Translation
+		if err := rows.Scan(&t.ID, &t.Locale, &t.Name, &t.IntName, &t.Provider, &t.Confidence); err != nil {
+			return count, err
+		}
+		if err := write(t); err != nil {
+			return count, err
+		}
+		count += 1
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
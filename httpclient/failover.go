@@ -0,0 +1,123 @@
+package httpclient
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Failover is an http.RoundTripper sending each request to the first healthy
+// endpoint of an ordered list. Endpoints failing with a network error or a
+// 5xx response are skipped for Cooldown, after which they are tried again,
+// so traffic returns to the primary once it recovers.
+type Failover struct {
+	Endpoints []*url.URL
+	Cooldown  time.Duration
+	Next      http.RoundTripper
+
+	mu   sync.Mutex
+	down map[int]time.Time
+}
+
+// NewFailover parses endpoints given as scheme://host[:port].
+func NewFailover(endpoints []string, cooldown time.Duration) (*Failover, error) {
+	f := &Failover{
+		Cooldown: cooldown,
+		Next:     http.DefaultTransport,
+		down:     map[int]time.Time{},
+	}
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("endpoint %q must be in scheme://host form", endpoint)
+		}
+		f.Endpoints = append(f.Endpoints, u)
+	}
+	if len(f.Endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints given")
+	}
+	return f, nil
+}
+
+func (f *Failover) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+	for _, i := range f.order() {
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		r := req.Clone(req.Context())
+		r.URL.Scheme = f.Endpoints[i].Scheme
+		r.URL.Host = f.Endpoints[i].Host
+		r.Host = ""
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			r.Body = body
+		}
+
+		resp, err = f.Next.RoundTrip(r)
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			f.markUp(i)
+			return resp, nil
+		}
+		f.markDown(i, err, resp)
+	}
+	return resp, err
+}
+
+// order lists healthy endpoints first, in configuration order, followed by
+// the ones still cooling down as a last resort.
+func (f *Failover) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var healthy, cooling []int
+	now := time.Now()
+	for i := range f.Endpoints {
+		if until, ok := f.down[i]; ok && now.Before(until) {
+			cooling = append(cooling, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+func (f *Failover) markUp(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.down[i]; ok {
+		delete(f.down, i)
+		log.Printf("Endpoint %s is back up\n", f.Endpoints[i].Host)
+	}
+}
+
+func (f *Failover) markDown(i int, err error, resp *http.Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	} else {
+		reason = resp.Status
+	}
+	f.down[i] = time.Now().Add(f.Cooldown)
+	log.Printf("Endpoint %s failed (%s), avoiding it for %s\n", f.Endpoints[i].Host, reason, f.Cooldown)
+}
@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// endpoint is a test server answering with status, counting its hits.
+type endpoint struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status int
+	hits   int
+}
+
+func newEndpoint(name string, status int) *endpoint {
+	e := &endpoint{status: status}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		e.hits += 1
+		status := e.status
+		e.mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(name))
+	}))
+	return e
+}
+
+func (e *endpoint) set(status int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status = status
+}
+
+func (e *endpoint) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.hits
+}
+
+// get sends a request for a placeholder host through the failover and
+// returns the status and the name of the endpoint that answered.
+func get(t *testing.T, f *Failover) (int, string, error) {
+	t.Helper()
+	client := &http.Client{Transport: f}
+	resp, err := client.Get("http://provider.invalid/search?q=x")
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+func newFailover(t *testing.T, cooldown time.Duration, endpoints ...string) *Failover {
+	t.Helper()
+	f, err := NewFailover(endpoints, cooldown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFailoverServerError(t *testing.T) {
+	primary := newEndpoint("primary", http.StatusServiceUnavailable)
+	defer primary.Close()
+	secondary := newEndpoint("secondary", http.StatusOK)
+	defer secondary.Close()
+	f := newFailover(t, time.Hour, primary.URL, secondary.URL)
+
+	for i := 0; i < 3; i++ {
+		status, name, err := get(t, f)
+		if err != nil || status != http.StatusOK || name != "secondary" {
+			t.Fatalf("request %d: %d from %q, %v, want 200 from secondary", i, status, name, err)
+		}
+	}
+	if n := primary.count(); n != 1 {
+		t.Errorf("primary got %d requests while cooling down, want 1", n)
+	}
+}
+
+func TestFailoverNetworkError(t *testing.T) {
+	primary := newEndpoint("primary", http.StatusOK)
+	primary.Close()
+	secondary := newEndpoint("secondary", http.StatusOK)
+	defer secondary.Close()
+	f := newFailover(t, time.Hour, primary.URL, secondary.URL)
+
+	status, name, err := get(t, f)
+	if err != nil || status != http.StatusOK || name != "secondary" {
+		t.Errorf("%d from %q, %v, want 200 from secondary", status, name, err)
+	}
+}
+
+func TestFailoverClientError(t *testing.T) {
+	primary := newEndpoint("primary", http.StatusNotFound)
+	defer primary.Close()
+	secondary := newEndpoint("secondary", http.StatusOK)
+	defer secondary.Close()
+	f := newFailover(t, time.Hour, primary.URL, secondary.URL)
+
+	status, name, err := get(t, f)
+	if err != nil || status != http.StatusNotFound || name != "primary" {
+		t.Errorf("%d from %q, %v, want 404 from primary", status, name, err)
+	}
+	if n := secondary.count(); n != 0 {
+		t.Errorf("secondary got %d requests, want 0", n)
+	}
+}
+
+func TestFailoverCooldownExpiry(t *testing.T) {
+	primary := newEndpoint("primary", http.StatusBadGateway)
+	defer primary.Close()
+	secondary := newEndpoint("secondary", http.StatusOK)
+	defer secondary.Close()
+	f := newFailover(t, 20*time.Millisecond, primary.URL, secondary.URL)
+
+	if _, name, err := get(t, f); err != nil || name != "secondary" {
+		t.Fatalf("answered by %q, %v, want secondary", name, err)
+	}
+	primary.set(http.StatusOK)
+	if _, name, err := get(t, f); err != nil || name != "secondary" {
+		t.Fatalf("answered by %q during cooldown, %v, want secondary", name, err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, name, err := get(t, f); err != nil || name != "primary" {
+		t.Errorf("answered by %q after cooldown, %v, want primary", name, err)
+	}
+}
+
+func TestFailoverSingleEndpoint(t *testing.T) {
+	only := newEndpoint("only", http.StatusServiceUnavailable)
+	defer only.Close()
+	f := newFailover(t, time.Hour, only.URL)
+
+	// the failing endpoint is still tried as the last resort
+	for i := 0; i < 2; i++ {
+		status, name, err := get(t, f)
+		if err != nil || status != http.StatusServiceUnavailable || name != "only" {
+			t.Fatalf("request %d: %d from %q, %v, want 503 from only", i, status, name, err)
+		}
+	}
+
+	only.set(http.StatusOK)
+	if status, _, err := get(t, f); err != nil || status != http.StatusOK {
+		t.Errorf("%d, %v after recovery, want 200", status, err)
+	}
+
+	only.Close()
+	if _, _, err := get(t, f); err == nil {
+		t.Error("no error from a closed endpoint")
+	}
+}
+
+func TestNewFailover(t *testing.T) {
+	for _, endpoints := range [][]string{nil, {"provider.example"}, {"http://"}, {"://bad"}} {
+		if _, err := NewFailover(endpoints, time.Minute); err == nil {
+			t.Errorf("NewFailover(%q) accepted invalid endpoints", endpoints)
+		}
+	}
+}
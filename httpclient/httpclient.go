@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/ratelimit"
+	"github.com/lensgolda/geocapture/settings"
+)
+
+// Client sends provider requests, retrying transient failures with
+// exponential backoff and jitter. Only bodies of 2xx responses are returned
+// to the caller; everything else becomes a *models.HTTPError.
+type Client struct {
+	HTTP       *http.Client
+	Limiter    *ratelimit.Limiter
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// New builds a client with the retry policy from settings. When endpoints
+// are given, requests fail over between them in order.
+func New(timeout time.Duration, limiter *ratelimit.Limiter, endpoints []string) (*Client, error) {
+	c := &Client{
+		HTTP: &http.Client{
+			Timeout: timeout,
+		},
+		Limiter:    limiter,
+		MaxRetries: settings.Config.HTTP.MaxRetries,
+		BaseDelay:  settings.Config.HTTP.BaseDelay,
+		MaxDelay:   settings.Config.HTTP.MaxDelay,
+	}
+	if len(endpoints) != 0 {
+		failover, err := NewFailover(endpoints, settings.Config.HTTP.FailoverCooldown)
+		if err != nil {
+			return nil, err
+		}
+		c.HTTP.Transport = failover
+	}
+	return c, nil
+}
+
+// Do sends req and returns the response body. Requests with a body must be
+// built so that req.GetBody is set, as http.NewRequest does for buffers.
+func (c *Client) Do(req *http.Request) ([]byte, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := c.do(ctx, req)
+		if err == nil {
+			return body, nil
+		}
+		if attempt >= c.MaxRetries || !transient(ctx, err) {
+			return nil, err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		log.Printf("%s %s: %s, retrying in %s\n", req.Method, req.URL.Host, err.Error(), delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request) ([]byte, time.Duration, error) {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	r := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, 0, err
+		}
+		r.Body = body
+	}
+
+	resp, err := c.HTTP.Do(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, _ = ioutil.ReadAll(resp.Body)
+		return nil, retryAfter(resp.Header.Get("Retry-After")), &models.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return body, 0, err
+}
+
+// backoff returns the delay before retry number attempt+1: the base delay
+// doubled per attempt, capped at MaxDelay, with the upper half jittered.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// transient tells whether a request may succeed when sent again.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var httpErr *models.HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// network errors and timeouts
+	return true
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+func TestBackoff(t *testing.T) {
+	c := &Client{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{5, 15 * time.Second, 30 * time.Second},
+		{10, 15 * time.Second, 30 * time.Second},
+		// the shift overflows
+		{80, 15 * time.Second, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if d := c.backoff(tt.attempt); d < tt.min || d > tt.max {
+					t.Fatalf("backoff(%d) = %s, want within [%s, %s]", tt.attempt, d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffWithoutJitter(t *testing.T) {
+	c := &Client{BaseDelay: time.Nanosecond, MaxDelay: time.Nanosecond}
+	if d := c.backoff(0); d != time.Nanosecond {
+		t.Errorf("backoff(0) = %s, want 1ns", d)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		min, max time.Duration
+	}{
+		{"empty", "", 0, 0},
+		{"seconds", "120", 2 * time.Minute, 2 * time.Minute},
+		{"zero", "0", 0, 0},
+		{"negative", "-5", 0, 0},
+		{"garbage", "soon", 0, 0},
+		{"http date", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{"past http date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := retryAfter(tt.value); d < tt.min || d > tt.max {
+				t.Errorf("retryAfter(%q) = %s, want within [%s, %s]", tt.value, d, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestTransient(t *testing.T) {
+	status := func(code int) error {
+		return &models.HTTPError{StatusCode: code, Status: http.StatusText(code)}
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"too many requests", context.Background(), status(http.StatusTooManyRequests), true},
+		{"internal server error", context.Background(), status(http.StatusInternalServerError), true},
+		{"bad gateway", context.Background(), status(http.StatusBadGateway), true},
+		{"service unavailable", context.Background(), status(http.StatusServiceUnavailable), true},
+		{"gateway timeout", context.Background(), status(http.StatusGatewayTimeout), true},
+		{"wrapped", context.Background(), fmt.Errorf("search: %w", status(http.StatusBadGateway)), true},
+		{"bad request", context.Background(), status(http.StatusBadRequest), false},
+		{"not found", context.Background(), status(http.StatusNotFound), false},
+		{"not implemented", context.Background(), status(http.StatusNotImplemented), false},
+		{"network", context.Background(), errors.New("connection reset by peer"), true},
+		{"canceled", canceled, status(http.StatusServiceUnavailable), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transient(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("transient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
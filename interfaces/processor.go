@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Processor runs a single record through lookup and storage.
+type Processor interface {
+	Process(ctx context.Context, model models.Model) error
+}
@@ -1,11 +1,29 @@
 package interfaces
 
 import (
-	"database/sql"
+	"context"
+
+	"github.com/lensgolda/geocapture/models"
 )
 
+// Provider resolves localized names for a city or country without
+// touching any storage.
 type Provider interface {
-	CountryNameLocalize(db *sql.DB)
-	CityNameLocalize(db *sql.DB)
+	ProviderName() string
 	FailedFile() string
+	Lookup(ctx context.Context, model models.Model) (*models.Result, error)
+}
+
+// BreakerReporter is implemented by providers guarded by circuit breakers.
+type BreakerReporter interface {
+	BreakerStates() []models.BreakerState
+}
+
+// BatchProvider is implemented by providers which resolve several records
+// with one request. LookupBatch returns a result or an error for every
+// record, in the order of batch.
+type BatchProvider interface {
+	Provider
+	BatchSize() int
+	LookupBatch(ctx context.Context, batch []models.Model) ([]*models.Result, []error)
 }
@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Sink persists lookup results produced by a Provider.
+type Sink interface {
+	Store(ctx context.Context, model models.Model, result *models.Result) error
+}
@@ -0,0 +1,271 @@
+package locales
+
+import "strings"
+
+// official holds the official languages of countries by their ISO 3166-1
+// alpha-2 code, as ISO 639 codes.
+var official = map[string][]string{
+	"AD": {"ca"},
+	"AE": {"ar"},
+	"AF": {"ps", "fa", "uz", "tk"},
+	"AG": {"en"},
+	"AI": {"en"},
+	"AL": {"sq"},
+	"AM": {"hy"},
+	"AO": {"pt"},
+	"AR": {"es"},
+	"AS": {"en", "sm"},
+	"AT": {"de"},
+	"AU": {"en"},
+	"AW": {"nl", "pap"},
+	"AX": {"sv"},
+	"AZ": {"az"},
+	"BA": {"bs", "hr", "sr"},
+	"BB": {"en"},
+	"BD": {"bn"},
+	"BE": {"nl", "fr", "de"},
+	"BF": {"fr"},
+	"BG": {"bg"},
+	"BH": {"ar"},
+	"BI": {"rn", "fr", "en"},
+	"BJ": {"fr"},
+	"BL": {"fr"},
+	"BM": {"en"},
+	"BN": {"ms"},
+	"BO": {"es", "qu", "ay", "gn"},
+	"BQ": {"nl", "pap"},
+	"BR": {"pt"},
+	"BS": {"en"},
+	"BT": {"dz"},
+	"BW": {"en", "tn"},
+	"BY": {"be", "ru"},
+	"BZ": {"en"},
+	"CA": {"en", "fr"},
+	"CC": {"en", "ms"},
+	"CD": {"fr", "ln", "sw", "kg", "lu"},
+	"CF": {"fr", "sg"},
+	"CG": {"fr", "ln"},
+	"CH": {"de", "fr", "it", "rm"},
+	"CI": {"fr"},
+	"CK": {"en"},
+	"CL": {"es"},
+	"CM": {"fr", "en"},
+	"CN": {"zh"},
+	"CO": {"es"},
+	"CR": {"es"},
+	"CU": {"es"},
+	"CV": {"pt"},
+	"CW": {"nl", "pap", "en"},
+	"CX": {"en"},
+	"CY": {"el", "tr"},
+	"CZ": {"cs"},
+	"DE": {"de"},
+	"DJ": {"fr", "ar"},
+	"DK": {"da"},
+	"DM": {"en"},
+	"DO": {"es"},
+	"DZ": {"ar", "fr"},
+	"EC": {"es", "qu"},
+	"EE": {"et"},
+	"EG": {"ar"},
+	"EH": {"ar", "es"},
+	"ER": {"ti", "ar", "en"},
+	"ES": {"es", "ca", "gl", "eu"},
+	"ET": {"am", "om", "ti", "so"},
+	"FI": {"fi", "sv"},
+	"FJ": {"en", "fj", "hif"},
+	"FK": {"en"},
+	"FM": {"en"},
+	"FO": {"fo", "da"},
+	"FR": {"fr"},
+	"GA": {"fr"},
+	"GB": {"en", "cy", "gd", "ga"},
+	"GD": {"en"},
+	"GE": {"ka"},
+	"GF": {"fr"},
+	"GG": {"en", "fr"},
+	"GH": {"en"},
+	"GI": {"en"},
+	"GL": {"kl", "da"},
+	"GM": {"en"},
+	"GN": {"fr"},
+	"GP": {"fr"},
+	"GQ": {"es", "fr", "pt"},
+	"GR": {"el"},
+	"GT": {"es"},
+	"GU": {"en", "ch"},
+	"GW": {"pt"},
+	"GY": {"en"},
+	"HK": {"zh", "en"},
+	"HN": {"es"},
+	"HR": {"hr"},
+	"HT": {"fr", "ht"},
+	"HU": {"hu"},
+	"ID": {"id"},
+	"IE": {"ga", "en"},
+	"IL": {"he", "ar"},
+	"IM": {"en", "gv"},
+	"IN": {"hi", "en", "as", "bn", "gu", "kn", "ml", "mr", "or", "pa", "ta", "te", "ur"},
+	"IO": {"en"},
+	"IQ": {"ar", "ku"},
+	"IR": {"fa"},
+	"IS": {"is"},
+	"IT": {"it"},
+	"JE": {"en", "fr"},
+	"JM": {"en"},
+	"JO": {"ar"},
+	"JP": {"ja"},
+	"KE": {"sw", "en"},
+	"KG": {"ky", "ru"},
+	"KH": {"km"},
+	"KI": {"en"},
+	"KM": {"ar", "fr"},
+	"KN": {"en"},
+	"KP": {"ko"},
+	"KR": {"ko"},
+	"KW": {"ar"},
+	"KY": {"en"},
+	"KZ": {"kk", "ru"},
+	"LA": {"lo"},
+	"LB": {"ar", "fr"},
+	"LC": {"en"},
+	"LI": {"de"},
+	"LK": {"si", "ta"},
+	"LR": {"en"},
+	"LS": {"st", "en"},
+	"LT": {"lt"},
+	"LU": {"lb", "fr", "de"},
+	"LV": {"lv"},
+	"LY": {"ar"},
+	"MA": {"ar", "fr", "zgh"},
+	"MC": {"fr"},
+	"MD": {"ro"},
+	"ME": {"sr", "bs", "hr", "sq"},
+	"MF": {"fr"},
+	"MG": {"mg", "fr"},
+	"MH": {"mh", "en"},
+	"MK": {"mk", "sq"},
+	"ML": {"fr", "bm"},
+	"MM": {"my"},
+	"MN": {"mn"},
+	"MO": {"zh", "pt"},
+	"MP": {"en", "ch"},
+	"MQ": {"fr"},
+	"MR": {"ar", "fr"},
+	"MS": {"en"},
+	"MT": {"mt", "en"},
+	"MU": {"en", "fr"},
+	"MV": {"dv"},
+	"MW": {"en", "ny"},
+	"MX": {"es"},
+	"MY": {"ms"},
+	"MZ": {"pt"},
+	"NA": {"en", "af", "de"},
+	"NC": {"fr"},
+	"NE": {"fr"},
+	"NF": {"en"},
+	"NG": {"en", "ha", "yo", "ig"},
+	"NI": {"es"},
+	"NL": {"nl", "fy"},
+	"NO": {"no", "nb", "nn", "se"},
+	"NP": {"ne"},
+	"NR": {"na", "en"},
+	"NU": {"en"},
+	"NZ": {"en", "mi"},
+	"OM": {"ar"},
+	"PA": {"es"},
+	"PE": {"es", "qu", "ay"},
+	"PF": {"fr"},
+	"PG": {"en", "tpi", "ho"},
+	"PH": {"fil", "tl", "en"},
+	"PK": {"ur", "en"},
+	"PL": {"pl"},
+	"PM": {"fr"},
+	"PN": {"en"},
+	"PR": {"es", "en"},
+	"PS": {"ar"},
+	"PT": {"pt"},
+	"PW": {"en"},
+	"PY": {"es", "gn"},
+	"QA": {"ar"},
+	"RE": {"fr"},
+	"RO": {"ro"},
+	"RS": {"sr"},
+	"RU": {"ru"},
+	"RW": {"rw", "en", "fr", "sw"},
+	"SA": {"ar"},
+	"SB": {"en"},
+	"SC": {"en", "fr", "crs"},
+	"SD": {"ar", "en"},
+	"SE": {"sv"},
+	"SG": {"en", "ms", "zh", "ta"},
+	"SH": {"en"},
+	"SI": {"sl"},
+	"SJ": {"no", "nb"},
+	"SK": {"sk"},
+	"SL": {"en"},
+	"SM": {"it"},
+	"SN": {"fr", "wo"},
+	"SO": {"so", "ar"},
+	"SR": {"nl"},
+	"SS": {"en"},
+	"ST": {"pt"},
+	"SV": {"es"},
+	"SX": {"nl", "en"},
+	"SY": {"ar"},
+	"SZ": {"en", "ss"},
+	"TC": {"en"},
+	"TD": {"fr", "ar"},
+	"TF": {"fr"},
+	"TG": {"fr"},
+	"TH": {"th"},
+	"TJ": {"tg"},
+	"TK": {"en", "tkl"},
+	"TL": {"pt", "tet"},
+	"TM": {"tk"},
+	"TN": {"ar", "fr"},
+	"TO": {"to", "en"},
+	"TR": {"tr"},
+	"TT": {"en"},
+	"TV": {"en"},
+	"TW": {"zh"},
+	"TZ": {"sw", "en"},
+	"UA": {"uk"},
+	"UG": {"en", "sw"},
+	"UM": {"en"},
+	"US": {"en"},
+	"UY": {"es"},
+	"UZ": {"uz"},
+	"VA": {"it", "la"},
+	"VC": {"en"},
+	"VE": {"es"},
+	"VG": {"en"},
+	"VI": {"en"},
+	"VN": {"vi"},
+	"VU": {"bi", "en", "fr"},
+	"WF": {"fr"},
+	"WS": {"sm", "en"},
+	"XK": {"sq", "sr"},
+	"YE": {"ar"},
+	"YT": {"fr"},
+	"ZA": {"af", "en", "nr", "nso", "ss", "st", "tn", "ts", "ve", "xh", "zu"},
+	"ZM": {"en"},
+	"ZW": {"en", "sn", "nd"},
+}
+
+// Foreign reports whether the language of locale is none of the official
+// languages of country, an ISO 3166-1 alpha-2 code. It is false when the
+// country is unknown.
+func Foreign(locale, country string) bool {
+	languages, ok := official[strings.ToUpper(country)]
+	if !ok {
+		return false
+	}
+	lang := strings.SplitN(Canonical(locale), "-", 2)[0]
+	for _, l := range languages {
+		if l == lang {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package locales
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// full matches the BCP 47 shapes in actual use: a two or three letter
+// language with optional extended language subtags, script, region and
+// variants, e.g. "kk", "zh-Hant-TW", "sr-Latn" or "be-tarask". The reserved
+// longer language subtags are left out so that keys such as Algolia's
+// "default" or OSM's "name:left" are not mistaken for locales.
+var full = regexp.MustCompile(`(?i)^[a-z]{2,3}(-[a-z]{3}){0,3}` +
+	`(-[a-z]{4})?` +
+	`(-([a-z]{2}|[0-9]{3}))?` +
+	`(-([a-z0-9]{5,8}|[0-9][a-z0-9]{3}))*$`)
+
+// Valid reports whether s is a well-formed BCP 47 language tag.
+func Valid(s string) bool {
+	return full.MatchString(s)
+}
+
+// Canonical returns s with the conventional casing of its subtags: language
+// and variants lower case, script title case, region upper case.
+func Canonical(s string) string {
+	parts := strings.Split(strings.Replace(s, "_", "-", -1), "-")
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 4 && isAlpha(p):
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		case len(p) == 2 && isAlpha(p):
+			parts[i] = strings.ToUpper(p)
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Parse validates a list of tags and returns them in canonical form without
+// duplicates.
+func Parse(list []string) ([]string, error) {
+	var (
+		parsed []string
+		seen   = map[string]bool{}
+	)
+	for _, s := range list {
+		s = Canonical(strings.TrimSpace(s))
+		if !Valid(s) {
+			return nil, fmt.Errorf("invalid locale %q, expected a BCP 47 tag such as ru or zh-Hant", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			parsed = append(parsed, s)
+		}
+	}
+	return parsed, nil
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
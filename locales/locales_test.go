@@ -0,0 +1,99 @@
+package locales
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"ru", true},
+		{"kk", true},
+		{"fil", true},
+		{"zh-Hant", true},
+		{"zh-Hant-TW", true},
+		{"sr-Latn", true},
+		{"es-419", true},
+		{"be-tarask", true},
+		{"de-CH-1996", true},
+		{"zh-yue-HK", true},
+		{"", false},
+		{"r", false},
+		{"default", false},
+		{"name:left", false},
+		{"en_US", false},
+		{"en-", false},
+		{"zh-Hant-Hans", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := Valid(tt.tag); got != tt.want {
+				t.Errorf("Valid(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"RU", "ru"},
+		{"zh-hant", "zh-Hant"},
+		{"ZH_hant_tw", "zh-Hant-TW"},
+		{"sr-LATN", "sr-Latn"},
+		{"en-us", "en-US"},
+		{"es-419", "es-419"},
+		{"be-TARASK", "be-tarask"},
+		{"de-ch-1996", "de-CH-1996"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := Canonical(tt.tag); got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse([]string{" ru", "EN", "zh_hant", "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ru", "en", "zh-Hant"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+	if _, err := Parse([]string{"ru", "default"}); err == nil {
+		t.Error("Parse() accepted an invalid locale")
+	}
+}
+
+func TestForeign(t *testing.T) {
+	tests := []struct {
+		locale  string
+		country string
+		want    bool
+	}{
+		{"ru", "KZ", false},
+		{"kk", "kz", false},
+		{"en", "KZ", true},
+		{"ru", "UZ", true},
+		{"zh-Hant", "TW", false},
+		{"sr-Latn", "RS", false},
+		{"fr", "CH", false},
+		{"ru", "", false},
+		{"ru", "ZZ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.country, func(t *testing.T) {
+			if got := Foreign(tt.locale, tt.country); got != tt.want {
+				t.Errorf("Foreign(%q, %q) = %v, want %v", tt.locale, tt.country, got, tt.want)
+			}
+		})
+	}
+}
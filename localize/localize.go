@@ -0,0 +1,350 @@
+package localize
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/lensgolda/geocapture/checkpoint"
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/logfile"
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Options narrows down which records a Runner processes and which locales
+// it stores. Zero values mean no restriction.
+type Options struct {
+	Limit   int
+	FromID  int
+	ToID    int
+	Locales []string
+	// Workers is the number of records looked up concurrently.
+	Workers int
+	// Resume starts after the last checkpointed ID when it is past FromID.
+	Resume bool
+	// Refresh only processes records already linked to an OSM object.
+	Refresh bool
+}
+
+// Runner reads cities or countries from the database, resolves them through
+// a Provider and hands the results to a Sink.
+type Runner struct {
+	DB       *sql.DB
+	Provider interfaces.Provider
+	Sink     interfaces.Sink
+	Options  Options
+	// Checkpoints, when set, receives the ID of every processed record.
+	Checkpoints *checkpoint.Store
+}
+
+var _ interfaces.Processor = (*Runner)(nil)
+
+func NewRunner(db *sql.DB, provider interfaces.Provider, sink interfaces.Sink) *Runner {
+	return &Runner{
+		DB:       db,
+		Provider: provider,
+		Sink:     sink,
+	}
+}
+
+// Process runs a single record through lookup and storage.
+func (r *Runner) Process(ctx context.Context, model models.Model) error {
+	result, err := r.Provider.Lookup(ctx, model)
+	if err != nil {
+		return err
+	}
+	return r.store(ctx, model, result)
+}
+
+// batchSize is the number of records looked up together, more than one only
+// for providers which resolve batches with a single request.
+func (r *Runner) batchSize() int {
+	if b, ok := r.Provider.(interfaces.BatchProvider); ok && b.BatchSize() > 1 {
+		return b.BatchSize()
+	}
+	return 1
+}
+
+// processBatch runs a batch of records through lookup and storage and
+// returns an error for every record.
+func (r *Runner) processBatch(ctx context.Context, batch []models.Model) []error {
+	b, ok := r.Provider.(interfaces.BatchProvider)
+	if !ok || len(batch) == 1 {
+		errs := make([]error, len(batch))
+		for i, model := range batch {
+			errs[i] = r.Process(ctx, model)
+		}
+		return errs
+	}
+
+	results, errs := b.LookupBatch(ctx, batch)
+	for i, model := range batch {
+		if errs[i] == nil {
+			errs[i] = r.store(ctx, model, results[i])
+		}
+	}
+	return errs
+}
+
+// store narrows a result down to Options.Locales and hands it to the Sink.
+func (r *Runner) store(ctx context.Context, model models.Model, result *models.Result) error {
+	if len(r.Options.Locales) != 0 {
+		names := map[string]string{}
+		for _, locale := range r.Options.Locales {
+			if name, ok := result.Names[locale]; ok {
+				names[locale] = name
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("%w: none of %v", models.ErrNoLocale, r.Options.Locales)
+		}
+		result.Names = names
+	}
+	if err := r.Sink.Store(ctx, model, result); err != nil {
+		return &models.DBError{Err: err}
+	}
+	return nil
+}
+
+// fromID returns the first ID to process, taking a checkpoint into account
+// when resuming.
+func (r *Runner) fromID(entity string) int {
+	fromID := r.Options.FromID
+	if r.Options.Resume && r.Checkpoints != nil {
+		if last := r.Checkpoints.Last(r.Provider.ProviderName(), r.checkpointKey(entity)); last >= fromID {
+			log.Printf("Resuming %s/%s after ID %d\n", r.Provider.ProviderName(), r.checkpointKey(entity), last)
+			fromID = last + 1
+		}
+	}
+	return fromID
+}
+
+// checkpointKey keeps the checkpoints of -refresh runs, which skip unlinked
+// records, apart from those of full runs.
+func (r *Runner) checkpointKey(entity string) string {
+	if r.Options.Refresh {
+		return entity + "-refresh"
+	}
+	return entity
+}
+
+// query appends the ID range, the refresh condition and the limit from
+// Options to a SELECT statement.
+func (r *Runner) query(base string, fromID int) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if fromID > 0 {
+		args = append(args, fromID)
+		conditions = append(conditions, fmt.Sprintf("id >= $%d", len(args)))
+	}
+	if r.Options.ToID > 0 {
+		args = append(args, r.Options.ToID)
+		conditions = append(conditions, fmt.Sprintf("id <= $%d", len(args)))
+	}
+	if r.Options.Refresh {
+		conditions = append(conditions, "osm_id IS NOT NULL")
+	}
+
+	query := base
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id"
+	if r.Options.Limit > 0 {
+		args = append(args, r.Options.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args
+}
+
+func (r *Runner) Countries(ctx context.Context) (*models.Summary, error) {
+	return r.run(ctx, "countries", models.CountriesQuery, func(rows *sql.Rows) (models.Model, error) {
+		return models.ScanCountry(rows)
+	})
+}
+
+func (r *Runner) Cities(ctx context.Context) (*models.Summary, error) {
+	return r.run(ctx, "cities", models.CitiesQuery, func(rows *sql.Rows) (models.Model, error) {
+		return models.ScanCity(rows)
+	})
+}
+
+// job is a batch of records handed to a worker; seq is its position in the
+// ID order and finished the number of leading records that went through,
+// all of them unless the run was interrupted.
+type job struct {
+	seq      int
+	batch    []models.Model
+	finished int
+}
+
+func (r *Runner) run(ctx context.Context, entity, query string, scan func(*sql.Rows) (models.Model, error)) (*models.Summary, error) {
+	summary := models.NewSummary(r.Provider.ProviderName(), entity)
+	defer func() {
+		if b, ok := r.Provider.(interfaces.BreakerReporter); ok {
+			summary.Breakers = b.BreakerStates()
+		}
+		summary.Done()
+	}()
+
+	failures, err := logfile.OpenLog(r.Provider.FailedFile())
+	if err != nil {
+		return summary, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		_ = failures.Close()
+	}()
+
+	query, args := r.query(query, r.fromID(entity))
+	rowsAll, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return summary, err
+	}
+	defer func() {
+		_ = rowsAll.Close()
+	}()
+
+	var mu sync.Mutex
+	fail := func(model models.Model, err error) {
+		mu.Lock()
+		summary.Failed += 1
+		mu.Unlock()
+		failures.Record(logfile.NewEntry(entity, model.Id(), r.Provider.ProviderName(), 1, err))
+		log.Println(err.Error())
+	}
+
+	workers := r.Options.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan job)
+	finished := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				errs := r.processBatch(ctx, j.batch)
+				interrupted := false
+				for i, err := range errs {
+					switch {
+					case ctx.Err() != nil && interruption(err):
+						// leave the record for the next run
+						interrupted = true
+						continue
+					case err != nil:
+						fail(j.batch[i], err)
+					default:
+						mu.Lock()
+						summary.Succeeded += 1
+						mu.Unlock()
+					}
+					if !interrupted {
+						j.finished = i + 1
+					}
+				}
+				finished <- j
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	tracked := make(chan struct{})
+	go func() {
+		defer close(tracked)
+		r.track(entity, finished)
+	}()
+
+	var (
+		seq   int
+		batch []models.Model
+	)
+	dispatch := func() {
+		select {
+		case jobs <- job{seq: seq, batch: batch}:
+			seq += 1
+		case <-ctx.Done():
+		}
+		batch = nil
+	}
+	size := r.batchSize()
+	for ctx.Err() == nil && rowsAll.Next() {
+		summary.Processed += 1
+		model, err := scan(rowsAll)
+		if err != nil {
+			fail(model, &models.DBError{Err: err})
+			continue
+		}
+		fmt.Printf("%d >>> %s: %s\n", summary.Processed, entity, model)
+
+		if batch = append(batch, model); len(batch) >= size {
+			dispatch()
+		}
+	}
+	if len(batch) != 0 && ctx.Err() == nil {
+		dispatch()
+	}
+	close(jobs)
+	<-tracked
+
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+	return summary, rowsAll.Err()
+}
+
+// track checkpoints the highest ID below which every dispatched record is
+// done, so that resuming never skips a record still in flight.
+func (r *Runner) track(entity string, finished <-chan job) {
+	var (
+		next    int
+		pending = map[int]job{}
+	)
+	for j := range finished {
+		pending[j.seq] = j
+		lastID := 0
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			if p.finished > 0 {
+				lastID = p.batch[p.finished-1].Id()
+			}
+			if p.finished < len(p.batch) {
+				// interrupted, nothing after it is done
+				break
+			}
+			delete(pending, next)
+			next += 1
+		}
+		if lastID != 0 {
+			r.checkpoint(entity, lastID)
+		}
+	}
+}
+
+// interruption tells whether err comes from the run being canceled rather
+// than from the record.
+func interruption(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (r *Runner) checkpoint(entity string, id int) {
+	if r.Checkpoints == nil {
+		return
+	}
+	if err := r.Checkpoints.Save(r.Provider.ProviderName(), r.checkpointKey(entity), id); err != nil {
+		log.Printf("Error saving checkpoint. Error: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,135 @@
+package localize
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lensgolda/geocapture/checkpoint"
+	"github.com/lensgolda/geocapture/models"
+)
+
+type stubProvider struct{}
+
+func (stubProvider) ProviderName() string { return "stub" }
+func (stubProvider) FailedFile() string   { return "stub.failed" }
+func (stubProvider) Lookup(context.Context, models.Model) (*models.Result, error) {
+	return models.NewResult("stub"), nil
+}
+
+// newRunner returns a runner with a checkpoint store in a new temporary
+// directory, which the caller removes.
+func newRunner(t *testing.T, opts Options) (*Runner, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "localize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := checkpoint.Open(filepath.Join(dir, "test.checkpoint"))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	r := NewRunner(nil, stubProvider{}, nil)
+	r.Options = opts
+	r.Checkpoints = store
+	return r, dir
+}
+
+// newJob builds the job seq of IDs of which the first finished went
+// through.
+func newJob(seq, finished int, ids ...int) job {
+	batch := make([]models.Model, len(ids))
+	for i, id := range ids {
+		batch[i] = models.City{ID: id}
+	}
+	return job{seq: seq, batch: batch, finished: finished}
+}
+
+func TestTrack(t *testing.T) {
+	tests := []struct {
+		name string
+		jobs []job
+		want int
+	}{
+		{"in order", []job{newJob(0, 2, 1, 2), newJob(1, 2, 3, 4)}, 4},
+		{"out of order", []job{newJob(1, 2, 3, 4), newJob(2, 1, 5), newJob(0, 2, 1, 2)}, 5},
+		{"gap", []job{newJob(0, 2, 1, 2), newJob(2, 2, 5, 6)}, 2},
+		{"first missing", []job{newJob(1, 2, 3, 4), newJob(2, 2, 5, 6)}, 0},
+		{"interrupted mid-batch", []job{newJob(0, 2, 1, 2), newJob(1, 1, 3, 4, 5), newJob(2, 1, 6)}, 3},
+		{"interrupted first batch", []job{newJob(1, 2, 3, 4), newJob(0, 2, 1, 2, 7)}, 2},
+		{"interrupted before any record", []job{newJob(0, 2, 1, 2), newJob(1, 0, 3, 4), newJob(2, 1, 5)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, dir := newRunner(t, Options{})
+			defer func() {
+				_ = os.RemoveAll(dir)
+			}()
+
+			finished := make(chan job, len(tt.jobs))
+			for _, j := range tt.jobs {
+				finished <- j
+			}
+			close(finished)
+			r.track("cities", finished)
+
+			if got := r.Checkpoints.Last("stub", "cities"); got != tt.want {
+				t.Errorf("checkpoint %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromID(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want int
+	}{
+		{"no resume", Options{FromID: 5}, 5},
+		{"resume", Options{Resume: true}, 11},
+		{"resume before from-id", Options{Resume: true, FromID: 20}, 20},
+		{"resume after from-id", Options{Resume: true, FromID: 11}, 11},
+		{"resume refresh", Options{Resume: true, Refresh: true}, 51},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, dir := newRunner(t, tt.opts)
+			defer func() {
+				_ = os.RemoveAll(dir)
+			}()
+			if err := r.Checkpoints.Save("stub", "cities", 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Checkpoints.Save("stub", "cities-refresh", 50); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := r.fromID("cities"); got != tt.want {
+				t.Errorf("fromID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshCheckpointKey(t *testing.T) {
+	r, dir := newRunner(t, Options{Refresh: true})
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	finished := make(chan job, 1)
+	finished <- newJob(0, 1, 40)
+	close(finished)
+	r.track("cities", finished)
+
+	if got := r.Checkpoints.Last("stub", "cities-refresh"); got != 40 {
+		t.Errorf("refresh checkpoint %d, want 40", got)
+	}
+	if got := r.Checkpoints.Last("stub", "cities"); got != 0 {
+		t.Errorf("full run checkpoint %d, want 0", got)
+	}
+}
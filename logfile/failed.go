@@ -1,145 +1,105 @@
 package logfile
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
-	"time"
 
 	"github.com/lensgolda/geocapture/interfaces"
 	"github.com/lensgolda/geocapture/models"
 )
 
-var (
-	city    models.City
-	country models.Country
-)
-
+// Failed re-processes records listed in a failure log through a provider's
+// lookup-and-store pipeline.
 type Failed struct {
-	File *os.File
+	Provider   interfaces.Provider
+	Processor  interfaces.Processor
+	Categories []Category
+	// Legacy retries bare IDs written by older versions as records of the
+	// entity being processed.
+	Legacy bool
+	// DryRun leaves the failure log as it is, since nothing is stored.
+	DryRun bool
 }
 
-func LogFailed(f *os.File, recordID int) {
-	_, err := f.WriteString(fmt.Sprintf("%d\n", recordID))
-	if err != nil {
-		log.Printf("Error writing to file. Error: %s\n", err.Error())
+func NewFailed(provider interfaces.Provider, processor interfaces.Processor) *Failed {
+	return &Failed{
+		Provider:  provider,
+		Processor: processor,
 	}
 }
 
-func (failed *Failed) ProcessFailedCities(db *sql.DB, fileName string, provider interfaces.Provider) {
-	f, err := os.OpenFile(provider.FailedFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal("Failed to open file: ", err)
-	}
-	defer func() {
-		_ = f.Close()
-	}()
-
-	failedDataFile, err := os.OpenFile(fileName, os.O_RDONLY, 0644)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-	defer func() {
-		_ = failedDataFile.Close()
-	}()
-
-	reader := bufio.NewReader(failedDataFile)
-	var line string
-	var counter uint = 0
-
-	for {
-		counter += 1
-
-		//limiter for tests
-		/*if counter >= 2 {
-			break
-		}*/
-
-		line, err = reader.ReadString('\n')
-		if err != nil {
-			break
-		}
-
-		row := db.QueryRow("SELECT id, name, name_national FROM cities WHERE id = $1", line)
-		if err := row.Scan(&city.ID, &city.Name, &city.NameNational); err != nil {
-			log.Println(err.Error())
-			LogFailed(f, city.ID)
-			continue
-		}
-		fmt.Printf("%d >>> CityID: %d, Name: %v\n", counter, city.ID, city.Name)
-
-		// TODO: process through provider
-
-		time.Sleep(time.Millisecond * 1100)
-
-	}
-
-	if err != io.EOF {
-		fmt.Printf(" > FAILED: %v\n", err)
-	}
+func (failed *Failed) ProcessFailedCities(ctx context.Context, db *sql.DB, fileName string) (*models.Summary, error) {
+	return failed.process(ctx, fileName, "cities", func(id int) (models.Model, error) {
+		return models.ScanCity(db.QueryRowContext(ctx, models.CitiesQuery+" WHERE id = $1", id))
+	})
+}
 
-	return
+func (failed *Failed) ProcessFailedCountries(ctx context.Context, db *sql.DB, fileName string) (*models.Summary, error) {
+	return failed.process(ctx, fileName, "countries", func(id int) (models.Model, error) {
+		return models.ScanCountry(db.QueryRowContext(ctx, models.CountriesQuery+" WHERE id = $1", id))
+	})
 }
 
-func (failed *Failed) ProcessFailedCountries(db *sql.DB, fileName string, provider interfaces.Provider) {
-	f, err := os.OpenFile(provider.FailedFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal("Failed to open file: ", err)
-	}
+// process retries the matching entries from fileName and, unless DryRun is
+// set, rewrites the file so that it keeps the entries which failed again,
+// were not reached or were filtered out.
+func (failed *Failed) process(ctx context.Context, fileName, entity string, load func(id int) (models.Model, error)) (*models.Summary, error) {
+	summary := models.NewSummary(failed.Provider.ProviderName(), entity)
 	defer func() {
-		_ = f.Close()
+		if b, ok := failed.Provider.(interfaces.BreakerReporter); ok {
+			summary.Breakers = b.BreakerStates()
+		}
+		summary.Done()
 	}()
 
-	failedDataFile, err := os.OpenFile(fileName, os.O_RDONLY, 0644)
+	matched, remaining, err := ReadFailed(fileName, entity, failed.Legacy, failed.Categories)
 	if err != nil {
-		log.Fatal(err.Error())
+		return summary, err
 	}
-	defer func() {
-		_ = failedDataFile.Close()
-	}()
-
-	reader := bufio.NewReader(failedDataFile)
-	var (
-		line    string
-		counter uint = 0
-		resp    *http.Response
-	)
-	defer func() {
-		_ = resp.Body.Close()
-	}()
 
-	for {
-		counter += 1
-		// limiter for tests
-		/*if counter >= 2 {
-			break
-		}*/
-
-		line, err = reader.ReadString('\n')
-		if err != nil {
+	var runErr error
+	for i, entry := range matched {
+		if runErr = ctx.Err(); runErr != nil {
+			remaining = append(remaining, matched[i:]...)
 			break
 		}
-
-		row := db.QueryRow("SELECT id, name, name_en FROM countries WHERE id = $1", line)
-		if err := row.Scan(&country.ID, &country.Name, &country.NameEN); err != nil {
-			log.Println(err.Error())
-			LogFailed(f, country.ID)
+		summary.Processed += 1
+
+		model, err := load(entry.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			summary.Failed += 1
+			if entry.Entity == "" {
+				// a bare ID may belong to the other entity, keep it as it was
+				log.Printf("No %s with ID %d, keeping the untagged entry in %s\n", entity, entry.ID, fileName)
+				remaining = append(remaining, entry)
+				continue
+			}
+			log.Printf("%s %d no longer exists, dropping it from %s\n", entity, entry.ID, fileName)
 			continue
 		}
-		fmt.Printf("%d >>> CountryID: %d, Name: %v\n", counter, country.ID, country.Name)
-
-		// TODO: process through provider
-
-		time.Sleep(time.Millisecond * 2500)
+		if err == nil {
+			fmt.Printf("%d >>> %s: %s\n", summary.Processed, entity, model)
+			err = failed.Processor.Process(ctx, model)
+		} else {
+			err = &models.DBError{Err: err}
+		}
+		if err != nil {
+			summary.Failed += 1
+			remaining = append(remaining, NewEntry(entity, entry.ID, failed.Provider.ProviderName(), entry.Attempt+1, err))
+			log.Println(err.Error())
+		} else {
+			summary.Succeeded += 1
+		}
 	}
 
-	if err != io.EOF {
-		fmt.Printf(" > Failed!: %v\n", err)
+	if failed.DryRun {
+		return summary, runErr
 	}
-
-	return
+	if err := WriteEntries(fileName, remaining); err != nil {
+		return summary, err
+	}
+	return summary, runErr
 }
@@ -0,0 +1,246 @@
+package logfile
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Category tells why a record failed.
+type Category string
+
+const (
+	CategoryNoName      Category = "no_name"
+	CategoryEmptyResult Category = "empty_result"
+	CategoryNoLocale    Category = "no_locale"
+	CategoryMismatch    Category = "mismatch"
+	CategoryNotLinked   Category = "not_linked"
+	CategoryParse       Category = "parse"
+	CategoryRateLimited Category = "rate_limited"
+	CategoryHTTP        Category = "http"
+	CategoryNetwork     Category = "network"
+	CategoryDatabase    Category = "database"
+	CategoryCanceled    Category = "canceled"
+	CategoryUnknown     Category = "unknown"
+)
+
+var categories = []Category{
+	CategoryNoName, CategoryEmptyResult, CategoryNoLocale, CategoryMismatch, CategoryNotLinked, CategoryParse, CategoryRateLimited,
+	CategoryHTTP, CategoryNetwork, CategoryDatabase, CategoryCanceled, CategoryUnknown,
+}
+
+func ParseCategory(s string) (Category, error) {
+	for _, c := range categories {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown failure category %q", s)
+}
+
+// Entry is a single line of a failure log.
+type Entry struct {
+	Entity     string    `json:"entity"`
+	ID         int       `json:"id"`
+	Provider   string    `json:"provider"`
+	Category   Category  `json:"category"`
+	HTTPStatus int       `json:"http_status,omitempty"`
+	Attempt    int       `json:"attempt"`
+	Error      string    `json:"error"`
+	Time       time.Time `json:"time"`
+}
+
+func NewEntry(entity string, id int, provider string, attempt int, err error) Entry {
+	category, status := Classify(err)
+	return Entry{
+		Entity:     entity,
+		ID:         id,
+		Provider:   provider,
+		Category:   category,
+		HTTPStatus: status,
+		Attempt:    attempt,
+		Error:      err.Error(),
+		Time:       time.Now().UTC(),
+	}
+}
+
+// Classify maps an error returned by the lookup-and-store pipeline to a
+// category and, for HTTP failures, the response status code.
+func Classify(err error) (Category, int) {
+	var (
+		httpErr   *models.HTTPError
+		dbErr     *models.DBError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		urlErr    *url.Error
+		netErr    net.Error
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled, 0
+	case errors.As(err, &httpErr):
+		if httpErr.StatusCode == http.StatusTooManyRequests {
+			return CategoryRateLimited, httpErr.StatusCode
+		}
+		return CategoryHTTP, httpErr.StatusCode
+	case errors.As(err, &dbErr):
+		return CategoryDatabase, 0
+	case errors.Is(err, models.ErrNoName):
+		return CategoryNoName, 0
+	case errors.Is(err, models.ErrEmptyResult):
+		return CategoryEmptyResult, 0
+	case errors.Is(err, models.ErrNoLocale):
+		return CategoryNoLocale, 0
+	case errors.Is(err, models.ErrNoMatch):
+		return CategoryMismatch, 0
+	case errors.Is(err, models.ErrNotLinked):
+		return CategoryNotLinked, 0
+	case errors.Is(err, models.ErrUnexpectedResponse), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return CategoryParse, 0
+	case errors.As(err, &urlErr), errors.As(err, &netErr):
+		return CategoryNetwork, 0
+	}
+	return CategoryUnknown, 0
+}
+
+// Log appends failure entries as JSON lines. It is safe for concurrent use.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func OpenLog(fileName string) (*Log, error) {
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (l *Log) Record(entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.enc.Encode(entry); err != nil {
+		log.Printf("Error writing to file. Error: %s\n", err.Error())
+	}
+}
+
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// ReadEntries loads a failure log keeping only the latest entry per entity
+// and ID. Lines holding a bare ID, as written by older versions, become
+// entries of unknown category and entity.
+func ReadEntries(fileName string) ([]Entry, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	type key struct {
+		entity string
+		id     int
+	}
+	var (
+		entries []Entry
+		index   = map[key]int{}
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry Entry
+		if id, err := strconv.Atoi(line); err == nil {
+			entry = Entry{ID: id, Category: CategoryUnknown, Attempt: 1}
+		} else if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			log.Printf("Skipping malformed line %q in %s\n", line, fileName)
+			continue
+		}
+
+		k := key{entry.Entity, entry.ID}
+		if i, ok := index[k]; ok {
+			entries[i] = entry
+			continue
+		}
+		index[k] = len(entries)
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// ReadFailed splits a failure log into the entries of the given entity
+// whose category is one of categories (any category when empty) and all
+// the others. Entries of unknown entity are only taken as entries of the
+// given one when legacy is set, since older versions wrote cities and
+// countries to the same file.
+func ReadFailed(fileName, entity string, legacy bool, categories []Category) (matched, rest []Entry, err error) {
+	entries, err := ReadEntries(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wanted := map[Category]bool{}
+	for _, c := range categories {
+		wanted[c] = true
+	}
+	for _, entry := range entries {
+		if (entry.Entity == entity || legacy && entry.Entity == "") && (len(wanted) == 0 || wanted[entry.Category]) {
+			matched = append(matched, entry)
+		} else {
+			rest = append(rest, entry)
+		}
+	}
+	return matched, rest, nil
+}
+
+// WriteEntries atomically replaces fileName with the given entries.
+func WriteEntries(fileName string, entries []Entry) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
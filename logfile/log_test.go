@@ -0,0 +1,168 @@
+package logfile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+func TestClassify(t *testing.T) {
+	var (
+		syntaxErr = json.Unmarshal([]byte("{"), &struct{}{})
+		typeErr   = json.Unmarshal([]byte(`{"a":"x"}`), &struct{ A int }{})
+	)
+	tests := []struct {
+		name     string
+		err      error
+		category Category
+		status   int
+	}{
+		{"canceled", fmt.Errorf("lookup: %w", context.Canceled), CategoryCanceled, 0},
+		{"rate limited", &models.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, CategoryRateLimited, 429},
+		{"http", &models.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}, CategoryHTTP, 503},
+		{"database", &models.DBError{Err: errors.New("connection refused")}, CategoryDatabase, 0},
+		{"no name", models.ErrNoName, CategoryNoName, 0},
+		{"empty result", fmt.Errorf("%w: Q1", models.ErrEmptyResult), CategoryEmptyResult, 0},
+		{"no locale", models.ErrNoLocale, CategoryNoLocale, 0},
+		{"mismatch", models.ErrNoMatch, CategoryMismatch, 0},
+		{"not linked", models.ErrNotLinked, CategoryNotLinked, 0},
+		{"unexpected response", models.ErrUnexpectedResponse, CategoryParse, 0},
+		{"json syntax", syntaxErr, CategoryParse, 0},
+		{"json type", typeErr, CategoryParse, 0},
+		{"network", &url.Error{Op: "Get", URL: "http://localhost", Err: errors.New("connection reset")}, CategoryNetwork, 0},
+		{"unknown", errors.New("boom"), CategoryUnknown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category, status := Classify(tt.err)
+			if category != tt.category || status != tt.status {
+				t.Errorf("Classify(%v) = %s, %d, want %s, %d", tt.err, category, status, tt.category, tt.status)
+			}
+		})
+	}
+}
+
+// writeLog writes content to a failure log in a new temporary directory,
+// which the caller removes.
+func writeLog(t *testing.T, content string) (string, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "logfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(dir, "test.failed")
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		_ = os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir, fileName
+}
+
+func TestReadEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Entry
+	}{
+		{
+			name:    "bare ids",
+			content: "12\n\n7\n",
+			want: []Entry{
+				{ID: 12, Category: CategoryUnknown, Attempt: 1},
+				{ID: 7, Category: CategoryUnknown, Attempt: 1},
+			},
+		},
+		{
+			name: "latest entry wins",
+			content: `{"entity":"cities","id":1,"category":"http","attempt":1}
+{"entity":"countries","id":1,"category":"no_name","attempt":1}
+{"entity":"cities","id":1,"category":"parse","attempt":2}
+`,
+			want: []Entry{
+				{Entity: "cities", ID: 1, Category: CategoryParse, Attempt: 2},
+				{Entity: "countries", ID: 1, Category: CategoryNoName, Attempt: 1},
+			},
+		},
+		{
+			name:    "bare id kept apart from tagged one",
+			content: "3\n" + `{"entity":"cities","id":3,"category":"http","attempt":1}` + "\n",
+			want: []Entry{
+				{ID: 3, Category: CategoryUnknown, Attempt: 1},
+				{Entity: "cities", ID: 3, Category: CategoryHTTP, Attempt: 1},
+			},
+		},
+		{
+			name:    "malformed lines skipped",
+			content: "not json\n" + `{"entity":"cities","id":5,"category":"network","attempt":3}` + "\n{\n",
+			want: []Entry{
+				{Entity: "cities", ID: 5, Category: CategoryNetwork, Attempt: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, fileName := writeLog(t, tt.content)
+			defer func() {
+				_ = os.RemoveAll(dir)
+			}()
+
+			got, err := ReadEntries(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadEntries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFailed(t *testing.T) {
+	content := "9\n" +
+		`{"entity":"cities","id":1,"category":"http","attempt":1}` + "\n" +
+		`{"entity":"cities","id":2,"category":"no_locale","attempt":1}` + "\n" +
+		`{"entity":"countries","id":1,"category":"http","attempt":1}` + "\n"
+
+	tests := []struct {
+		name       string
+		entity     string
+		legacy     bool
+		categories []Category
+		matched    []int
+		rest       int
+	}{
+		{"entity only", "cities", false, nil, []int{1, 2}, 2},
+		{"other entity", "countries", false, nil, []int{1}, 3},
+		{"legacy ids", "countries", true, nil, []int{9, 1}, 2},
+		{"categories", "cities", true, []Category{CategoryHTTP}, []int{1}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, fileName := writeLog(t, content)
+			defer func() {
+				_ = os.RemoveAll(dir)
+			}()
+
+			matched, rest, err := ReadFailed(fileName, tt.entity, tt.legacy, tt.categories)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, entry := range matched {
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.matched) || len(rest) != tt.rest {
+				t.Errorf("ReadFailed() matched %v and %d others, want %v and %d", ids, len(rest), tt.matched, tt.rest)
+			}
+		})
+	}
+}
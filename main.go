@@ -1,60 +1,189 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"os"
+	"os/signal"
+	"strings"
 	"time"
 
+	"github.com/lensgolda/geocapture/breaker"
 	"github.com/lensgolda/geocapture/interfaces"
-	"github.com/lensgolda/geocapture/providers/nominatim"
+	"github.com/lensgolda/geocapture/providers"
 	"github.com/lensgolda/geocapture/settings"
 
+	_ "github.com/lensgolda/geocapture/providers/algolia"
+	_ "github.com/lensgolda/geocapture/providers/geonames"
+	_ "github.com/lensgolda/geocapture/providers/mapquest"
+	_ "github.com/lensgolda/geocapture/providers/nominatim"
+	_ "github.com/lensgolda/geocapture/providers/opencage"
+	_ "github.com/lensgolda/geocapture/providers/pelias"
+	_ "github.com/lensgolda/geocapture/providers/photon"
+	_ "github.com/lensgolda/geocapture/providers/wikidata"
+
 	_ "github.com/joho/godotenv/autoload"
 	_ "github.com/lib/pq"
 )
 
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"localize", "localize countries|cities through the selected providers", runLocalize},
+	{"retry-failed", "re-process records listed in the providers' .failed files", runRetryFailed},
+	{"providers", "list registered providers and their required settings", runProviders},
+	{"export", "export stored translations as csv or json", runExport},
+	{"check-config", "validate settings, providers and database connection", runCheckConfig},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: geocapture <command> [arguments]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "    %-14s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'geocapture <command> -h' for command flags.\n")
+}
+
 func main() {
-	fmt.Println("Start...")
-	fmt.Print("Loading configuration...")
-	if err := settings.LoadSettings(); err != nil {
-		fmt.Printf("ERROR during settings loading: %s", err.Error())
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+			os.Exit(1)
+		}
 		return
 	}
-	time.Sleep(time.Second * 1)
-	fmt.Printf("OK\n")
 
-	/* Init local db connection */
+	if name != "-h" && name != "--help" && name != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
+
+func loadSettings() error {
+	fmt.Fprint(os.Stderr, "Loading configuration...")
+	if err := settings.LoadSettings(); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR")
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "OK\n")
+	return nil
+}
+
+/* Init local db connection */
+func connect(ctx context.Context) (*sql.DB, error) {
 	connStr := fmt.Sprintf(
 		"postgres://postgres:@%s/%s?sslmode=%s",
 		settings.Config.DB.Host,
 		settings.Config.DB.Name,
 		settings.Config.DB.SSL,
 	)
-	fmt.Printf("Connecting to database: %s...", connStr)
+	fmt.Fprintf(os.Stderr, "Connecting to database: %s...", connStr)
 
 	db, err := sql.Open("postgres", connStr)
-	defer func() {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR")
+		return nil, err
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR")
 		_ = db.Close()
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "OK\n")
+	return db, nil
+}
+
+// interruptible returns a context cancelled on the first interrupt signal.
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			fmt.Fprintln(os.Stderr, "Interrupted, stopping...")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(interrupt)
 	}()
+	return ctx, cancel
+}
 
-	if err != nil {
-		log.Fatal(err)
-	}
-	time.Sleep(time.Second * 1)
-	fmt.Printf("OK\n")
-
-	/*
-	 * Create provider
-	 * var algoliaProvider models.Provider = algolia.NewProvider()
-	 * var mapqProvider models.Provider = mapquest.NewProvider()
-	 */
-	var nomProvider interfaces.Provider = nominatim.NewProvider()
-
-	/*
-	 * Process from local db or from file
-	 * logfile.ProcessFailedCountries(db, "nominatim.failed", nomProvider)
-	 */
-	nomProvider.CountryNameLocalize(db)
-	fmt.Println("Success...OK")
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// selectedProviders builds the providers named in the -provider flag,
+// falling back to GEOCAPTURE_PROVIDER, each guarded by a circuit breaker.
+// While a breaker is open lookups go to the fallback provider, if any.
+func selectedProviders(names, fallbackName string) ([]interfaces.Provider, error) {
+	list := settings.Config.App.Providers
+	if names != "" {
+		list = splitList(names)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no provider selected, available: %s", strings.Join(providers.Names(), ", "))
+	}
+
+	// one instance per provider so that rate limits and breakers are shared
+	built := map[string]*breaker.Provider{}
+	build := func(name string) (*breaker.Provider, error) {
+		if provider, ok := built[name]; ok {
+			return provider, nil
+		}
+		provider, err := providers.New(name)
+		if err != nil {
+			return nil, err
+		}
+		built[name] = breaker.Wrap(provider, settings.Config.Breaker.Threshold, settings.Config.Breaker.Cooldown)
+		return built[name], nil
+	}
+
+	var fallback *breaker.Provider
+	if fallbackName != "" {
+		provider, err := build(fallbackName)
+		if err != nil {
+			return nil, err
+		}
+		fallback = provider
+	}
+
+	var selected []interfaces.Provider
+	for _, name := range list {
+		provider, err := build(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if fallback != nil && provider != fallback {
+			provider.Fallback = fallback
+		}
+		selected = append(selected, provider)
+	}
+	return selected, nil
 }
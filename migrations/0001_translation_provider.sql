@@ -0,0 +1,7 @@
+-- Records the provider behind every translation and, for names chosen by
+-- consensus, the share of providers that agreed on it.
+ALTER TABLE cities_translations ADD COLUMN IF NOT EXISTS provider text;
+ALTER TABLE cities_translations ADD COLUMN IF NOT EXISTS confidence real;
+
+ALTER TABLE countries_translations_temp ADD COLUMN IF NOT EXISTS provider text;
+ALTER TABLE countries_translations_temp ADD COLUMN IF NOT EXISTS confidence real;
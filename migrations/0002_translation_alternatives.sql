@@ -0,0 +1,25 @@
+-- Alternative names per locale, ranked, with the OSM tag they came from and
+-- the providers which returned them.
+CREATE TABLE IF NOT EXISTS cities_translations_alternatives (
+    city_id    integer NOT NULL,
+    locale     text    NOT NULL,
+    name       text    NOT NULL,
+    kind       text    NOT NULL DEFAULT 'name',
+    providers  text    NOT NULL,
+    confidence real,
+    rank       integer NOT NULL
+);
+CREATE INDEX IF NOT EXISTS cities_translations_alternatives_city_id_locale_idx
+    ON cities_translations_alternatives (city_id, locale);
+
+CREATE TABLE IF NOT EXISTS countries_translations_alternatives (
+    country_id integer NOT NULL,
+    locale     text    NOT NULL,
+    name       text    NOT NULL,
+    kind       text    NOT NULL DEFAULT 'name',
+    providers  text    NOT NULL,
+    confidence real,
+    rank       integer NOT NULL
+);
+CREATE INDEX IF NOT EXISTS countries_translations_alternatives_country_id_locale_idx
+    ON countries_translations_alternatives (country_id, locale);
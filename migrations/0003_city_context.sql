@@ -0,0 +1,4 @@
+-- Country and region the city lookups are narrowed to.
+ALTER TABLE countries ADD COLUMN IF NOT EXISTS code char(2);
+ALTER TABLE cities ADD COLUMN IF NOT EXISTS country_id integer;
+ALTER TABLE cities ADD COLUMN IF NOT EXISTS region text;
@@ -0,0 +1,33 @@
+-- Position, OSM object and Wikidata item of the matched place, one row per
+-- record and provider.
+CREATE TABLE IF NOT EXISTS cities_geo_attributes (
+    city_id    integer          NOT NULL,
+    provider   text             NOT NULL,
+    lat        double precision NOT NULL,
+    lon        double precision NOT NULL,
+    osm_type   text,
+    osm_id     bigint,
+    place_rank integer,
+    bbox_south double precision,
+    bbox_north double precision,
+    bbox_west  double precision,
+    bbox_east  double precision,
+    wikidata   text,
+    UNIQUE (city_id, provider)
+);
+
+CREATE TABLE IF NOT EXISTS countries_geo_attributes (
+    country_id integer          NOT NULL,
+    provider   text             NOT NULL,
+    lat        double precision NOT NULL,
+    lon        double precision NOT NULL,
+    osm_type   text,
+    osm_id     bigint,
+    place_rank integer,
+    bbox_south double precision,
+    bbox_north double precision,
+    bbox_west  double precision,
+    bbox_east  double precision,
+    wikidata   text,
+    UNIQUE (country_id, provider)
+);
@@ -0,0 +1,3 @@
+-- Known position of a city, used by -reverse.
+ALTER TABLE cities ADD COLUMN IF NOT EXISTS lat double precision;
+ALTER TABLE cities ADD COLUMN IF NOT EXISTS lon double precision;
@@ -0,0 +1,12 @@
+-- Records the provider whose result stored each alternative, so that storing
+-- a record again only replaces the alternatives of the same provider. Rows of
+-- earlier runs are attributed to the first provider which returned them.
+ALTER TABLE cities_translations_alternatives ADD COLUMN IF NOT EXISTS provider text;
+UPDATE cities_translations_alternatives SET provider = split_part(providers, ',', 1) WHERE provider IS NULL;
+CREATE INDEX IF NOT EXISTS cities_translations_alternatives_city_id_locale_provider_idx
+    ON cities_translations_alternatives (city_id, locale, provider);
+
+ALTER TABLE countries_translations_alternatives ADD COLUMN IF NOT EXISTS provider text;
+UPDATE countries_translations_alternatives SET provider = split_part(providers, ',', 1) WHERE provider IS NULL;
+CREATE INDEX IF NOT EXISTS countries_translations_alternatives_country_id_locale_provider_idx
+    ON countries_translations_alternatives (country_id, locale, provider);
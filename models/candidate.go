@@ -0,0 +1,65 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Candidate is the place context a provider returned for a search result,
+// used to tell apart places sharing a name.
+type Candidate struct {
+	// CountryCode is the ISO 3166-1 alpha-2 code of the result's country.
+	CountryCode string
+	// Regions lists the administrative areas the result lies in.
+	Regions []string
+}
+
+// Rank returns the indexes of the candidates which may be the place model
+// stands for, best first. Candidates of cities lying in another country than
+// the city's are rejected, a matching region moves a candidate up. Equally
+// scored candidates keep the provider's order. ErrNoMatch is returned when
+// every candidate was rejected.
+func Rank(model Model, candidates []Candidate) ([]int, error) {
+	city, ok := model.(City)
+	indexes := make([]int, 0, len(candidates))
+	scores := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		if ok {
+			score, match := city.score(candidate)
+			if !match {
+				continue
+			}
+			scores[i] = score
+		}
+		indexes = append(indexes, i)
+	}
+	if len(indexes) == 0 {
+		return nil, ErrNoMatch
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+	return indexes, nil
+}
+
+// score rates how well candidate fits the city. Unknown countries on either
+// side neither reject nor favor a candidate.
+func (m City) score(candidate Candidate) (int, bool) {
+	score := 0
+	if m.CountryCode != nil && *m.CountryCode != "" && candidate.CountryCode != "" {
+		if !strings.EqualFold(*m.CountryCode, candidate.CountryCode) {
+			return 0, false
+		}
+		score += 2
+	}
+	if m.Region != nil && *m.Region != "" {
+		region := NormalizeName(*m.Region)
+		for _, r := range candidate.Regions {
+			if r = NormalizeName(r); r != "" && (strings.Contains(r, region) || strings.Contains(region, r)) {
+				score++
+				break
+			}
+		}
+	}
+	return score, true
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoName             = errors.New("record has no name to search for")
+	ErrEmptyResult        = errors.New("response data have zero length")
+	ErrNoLocale           = errors.New("response data doesn't contain appropriate locale")
+	ErrUnexpectedResponse = errors.New("nested data error, see data nested types and values")
+	ErrWrongModel         = errors.New("wrong model type")
+	ErrNoMatch            = errors.New("no result lies in the record's country")
+	ErrNotLinked          = errors.New("record isn't linked to a Wikidata item")
+)
+
+// HTTPError is returned when a provider answers with a non-2xx status.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected response status: %s", e.Status)
+}
+
+// DBError wraps failures to read records from or write results to the
+// database, so they can be told apart from provider failures.
+type DBError struct {
+	Err error
+}
+
+func (e *DBError) Error() string {
+	return "database: " + e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
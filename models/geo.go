@@ -0,0 +1,159 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// OSM object types as returned in osm_type.
+const (
+	OSMNode     = "node"
+	OSMWay      = "way"
+	OSMRelation = "relation"
+)
+
+// Geo is the position of a found place and, for OSM based providers, the
+// OSM object it was matched to.
+type Geo struct {
+	Provider  string  `json:"provider"`
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	OSMType   string  `json:"osm_type,omitempty"`
+	OSMID     int64   `json:"osm_id,omitempty"`
+	PlaceRank int     `json:"place_rank,omitempty"`
+	// BoundingBox is south, north, west, east as in Nominatim responses.
+	BoundingBox []float64 `json:"boundingbox,omitempty"`
+	// Wikidata is the Q-ID of the Wikidata item for the place.
+	Wikidata string `json:"wikidata,omitempty"`
+}
+
+// GeoLoc is the position of an Algolia hit.
+type GeoLoc struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Geo returns the position, OSM object and Wikidata item of the location,
+// or nil when the response had no coordinates. Nominatim sends numbers as
+// strings, some compatible services don't, json.Number accepts both.
+func (l Location) Geo(provider string) *Geo {
+	lat, err1 := strconv.ParseFloat(string(l.Lat), 64)
+	lon, err2 := strconv.ParseFloat(string(l.Lon), 64)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	geo := &Geo{
+		Provider:  provider,
+		Lat:       lat,
+		Lon:       lon,
+		OSMType:   OSMType(l.OSMType),
+		PlaceRank: l.PlaceRank,
+		Wikidata:  l.ExtraTags["wikidata"],
+	}
+	if id, err := l.OSMID.Int64(); err == nil {
+		geo.OSMID = id
+	}
+	if len(l.BoundingBox) == 4 {
+		for _, n := range l.BoundingBox {
+			f, err := n.Float64()
+			if err != nil {
+				geo.BoundingBox = nil
+				break
+			}
+			geo.BoundingBox = append(geo.BoundingBox, f)
+		}
+	}
+	return geo
+}
+
+// Geo returns the position of the hit, or nil when it has none.
+func (h Hit) Geo(provider string) *Geo {
+	if h.GeoLoc == nil {
+		return nil
+	}
+	return &Geo{Provider: provider, Lat: h.GeoLoc.Lat, Lon: h.GeoLoc.Lng}
+}
+
+// OSMRef identifies an OSM object a record has been linked to.
+type OSMRef struct {
+	Type string
+	ID   int64
+}
+
+// NewOSMRef builds a reference from nullable database columns, returning nil
+// when the record isn't linked.
+func NewOSMRef(osmType *string, osmID *int64) *OSMRef {
+	if osmType == nil || osmID == nil {
+		return nil
+	}
+	ref := &OSMRef{Type: OSMType(*osmType), ID: *osmID}
+	if ref.Type == "" {
+		return nil
+	}
+	return ref
+}
+
+// OSMType maps the full and the single letter spelling of an OSM type to
+// the full one, returning "" for anything else.
+func OSMType(s string) string {
+	switch strings.ToLower(s) {
+	case "n", OSMNode:
+		return OSMNode
+	case "w", OSMWay:
+		return OSMWay
+	case "r", OSMRelation:
+		return OSMRelation
+	}
+	return ""
+}
+
+// Letter returns the single letter type used by Nominatim's osm_ids and
+// osmtype parameters.
+func (r OSMRef) Letter() string {
+	return strings.ToUpper(r.Type[:1])
+}
+
+// String returns the reference as N123, W123 or R123.
+func (r OSMRef) String() string {
+	return r.Letter() + strconv.FormatInt(r.ID, 10)
+}
+
+// WikidataOf returns the Q-ID of the Wikidata item model has been linked to,
+// or "".
+func WikidataOf(model Model) string {
+	var id *string
+	switch m := model.(type) {
+	case City:
+		id = m.Wikidata
+	case Country:
+		id = m.Wikidata
+	}
+	if id == nil {
+		return ""
+	}
+	return *id
+}
+
+// WithWikidata returns a copy of model linked to the Wikidata item id.
+func WithWikidata(model Model, id string) Model {
+	switch m := model.(type) {
+	case City:
+		m.Wikidata = &id
+		return m
+	case Country:
+		m.Wikidata = &id
+		return m
+	}
+	return model
+}
+
+// Linked returns the OSM object model has been linked to, if any.
+func Linked(model Model) *OSMRef {
+	switch m := model.(type) {
+	case City:
+		return m.OSM
+	case Country:
+		return m.OSM
+	}
+	return nil
+}
@@ -1,40 +1,405 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lensgolda/geocapture/locales"
+)
+
 type City struct {
 	ID           int
 	Name         *string
 	NameNational *string
+	// CountryCode, CountryName and Region come from the city's country and
+	// administrative area and narrow down the search when present.
+	CountryCode *string
+	CountryName *string
+	Region      *string
+	// OSM is the OSM object the city was linked to by an earlier lookup.
+	OSM *OSMRef
+	// Wikidata is the Q-ID of the Wikidata item the city was linked to.
+	Wikidata *string
+	// Lat and Lon are the city's own coordinates, used for reverse lookups.
+	Lat *float64
+	Lon *float64
+}
+
+// Coordinates returns the city's coordinates when both are known.
+func (m City) Coordinates() (float64, float64, bool) {
+	if m.Lat == nil || m.Lon == nil {
+		return 0, 0, false
+	}
+	return *m.Lat, *m.Lon, true
 }
 
 type Country struct {
 	ID     int
 	Name   *string
 	NameEN *string
+	// OSM is the OSM object the country was linked to by an earlier lookup.
+	OSM *OSMRef
+	// Wikidata is the Q-ID of the Wikidata item the country was linked to.
+	Wikidata *string
+}
+
+// NameDetails holds the OSM name tags returned in namedetails by Nominatim
+// compatible services, e.g. "name", "name:ru" or "int_name".
+type NameDetails map[string]string
+
+// Localized returns the value of every "name:<locale>" tag whose suffix is a
+// valid language tag, keyed by the canonical locale.
+func (nd NameDetails) Localized() map[string]string {
+	names := map[string]string{}
+	for key, value := range nd {
+		if !strings.HasPrefix(key, "name:") || value == "" {
+			continue
+		}
+		locale := locales.Canonical(strings.TrimPrefix(key, "name:"))
+		if locales.Valid(locale) {
+			names[locale] = value
+		}
+	}
+	return names
+}
+
+// Alternatives returns the official, short, alternative, old and local
+// names of every locale in ranking order, splitting ";" separated values.
+func (nd NameDetails) Alternatives(provider string) map[string][]Alternative {
+	alternatives := map[string][]Alternative{}
+	for _, kind := range AlternativeKinds {
+		for key, value := range nd {
+			if !strings.HasPrefix(key, kind+":") {
+				continue
+			}
+			locale := locales.Canonical(strings.TrimPrefix(key, kind+":"))
+			if !locales.Valid(locale) {
+				continue
+			}
+			for _, name := range strings.Split(value, ";") {
+				if name = strings.TrimSpace(name); name != "" {
+					alternatives[locale] = append(alternatives[locale], Alternative{
+						Name:      name,
+						Kind:      kind,
+						Providers: []string{provider},
+					})
+				}
+			}
+		}
+	}
+	return alternatives
+}
+
+// AddAlternatives adds the localized names and alternative name tags of nd
+// to the alternatives of result. Names equal to the chosen ones are skipped.
+func (nd NameDetails) AddAlternatives(result *Result) {
+	for locale, name := range nd.Localized() {
+		result.AddAlternative(locale, Alternative{Name: name, Kind: KindName, Providers: []string{result.Provider}})
+	}
+	for locale, alternatives := range nd.Alternatives(result.Provider) {
+		for _, alt := range alternatives {
+			result.AddAlternative(locale, alt)
+		}
+	}
 }
 
-type AltName struct {
-	NameRu  *string `json:"name:ru"`
-	NameEn  *string `json:"name:en"`
-	NameKk  *string `json:"name:kk"`
-	NameUk  *string `json:"name:uk"`
-	IntName *string `json:"int_name"`
+func (nd NameDetails) IntName() *string {
+	if intName, ok := nd["int_name"]; ok {
+		return &intName
+	}
+	return nil
 }
 
 type Location struct {
-	Namedetail AltName `json:"namedetails"`
+	Namedetail  NameDetails       `json:"namedetails"`
+	Address     map[string]string `json:"address"`
+	Lat         json.Number       `json:"lat"`
+	Lon         json.Number       `json:"lon"`
+	OSMType     string            `json:"osm_type"`
+	OSMID       json.Number       `json:"osm_id"`
+	PlaceRank   int               `json:"place_rank"`
+	BoundingBox []json.Number     `json:"boundingbox"`
+	ExtraTags   map[string]string `json:"extratags"`
+}
+
+// Candidate returns the country code and administrative areas from the
+// addressdetails of the location.
+func (l Location) Candidate() Candidate {
+	candidate := Candidate{CountryCode: l.Address["country_code"]}
+	for _, key := range []string{"state", "region", "province", "county", "state_district"} {
+		if area := l.Address[key]; area != "" {
+			candidate.Regions = append(candidate.Regions, area)
+		}
+	}
+	return candidate
 }
 
 type NomResult []Location
 
+// NomReverse is the response of the /reverse endpoint of Nominatim
+// compatible services, which report a miss in Error.
+type NomReverse struct {
+	Location
+	Error string `json:"error"`
+}
+
+// NomDetails is the response of Nominatim's /details endpoint.
+type NomDetails struct {
+	OSMType     string            `json:"osm_type"`
+	OSMID       int64             `json:"osm_id"`
+	Names       NameDetails       `json:"names"`
+	CountryCode string            `json:"country_code"`
+	RankSearch  int               `json:"rank_search"`
+	ExtraTags   map[string]string `json:"extratags"`
+	Centroid    struct {
+		// Coordinates is lon, lat as in GeoJSON.
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"centroid"`
+}
+
+// Geo returns the centroid and OSM object of the details.
+func (d NomDetails) Geo(provider string) *Geo {
+	geo := &Geo{
+		Provider:  provider,
+		OSMType:   OSMType(d.OSMType),
+		OSMID:     d.OSMID,
+		PlaceRank: d.RankSearch,
+		Wikidata:  d.ExtraTags["wikidata"],
+	}
+	if len(d.Centroid.Coordinates) == 2 {
+		geo.Lon, geo.Lat = d.Centroid.Coordinates[0], d.Centroid.Coordinates[1]
+	}
+	return geo
+}
+
 type Hit struct {
-	IsCity      bool `json:"is_city"`
-	IsCountry   bool `json:"is_country"`
-	LocaleNames map[string][]string
+	IsCity         bool                `json:"is_city"`
+	IsCountry      bool                `json:"is_country"`
+	LocaleNames    map[string][]string `json:"locale_names"`
+	CountryCode    string              `json:"country_code"`
+	Administrative []string            `json:"administrative"`
+	GeoLoc         *GeoLoc             `json:"_geoloc"`
+}
+
+// Candidate returns the country code and administrative areas of the hit.
+func (h Hit) Candidate() Candidate {
+	return Candidate{CountryCode: h.CountryCode, Regions: h.Administrative}
 }
+
 type AlgResult struct {
 	Hit []Hit `json:"hits"`
 }
 
+// PhotonResult is the GeoJSON FeatureCollection returned by Photon.
+type PhotonResult struct {
+	Features []PhotonFeature `json:"features"`
+}
+
+type PhotonFeature struct {
+	Geometry struct {
+		// Coordinates is lon, lat as in GeoJSON.
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Name        string `json:"name"`
+		OSMType     string `json:"osm_type"`
+		OSMID       int64  `json:"osm_id"`
+		OSMKey      string `json:"osm_key"`
+		OSMValue    string `json:"osm_value"`
+		CountryCode string `json:"countrycode"`
+		State       string `json:"state"`
+		County      string `json:"county"`
+		// Extent is min lon, max lat, max lon, min lat.
+		Extent []float64 `json:"extent"`
+	} `json:"properties"`
+}
+
+// Candidate returns the country code and administrative areas of the feature.
+func (f PhotonFeature) Candidate() Candidate {
+	candidate := Candidate{CountryCode: f.Properties.CountryCode}
+	for _, area := range []string{f.Properties.State, f.Properties.County} {
+		if area != "" {
+			candidate.Regions = append(candidate.Regions, area)
+		}
+	}
+	return candidate
+}
+
+// Ref returns the OSM object of the feature.
+func (f PhotonFeature) Ref() OSMRef {
+	return OSMRef{Type: OSMType(f.Properties.OSMType), ID: f.Properties.OSMID}
+}
+
+// Geo returns the position, OSM object and extent of the feature.
+func (f PhotonFeature) Geo(provider string) *Geo {
+	geo := &Geo{
+		Provider: provider,
+		OSMType:  OSMType(f.Properties.OSMType),
+		OSMID:    f.Properties.OSMID,
+	}
+	if c := f.Geometry.Coordinates; len(c) == 2 {
+		geo.Lon, geo.Lat = c[0], c[1]
+	}
+	if e := f.Properties.Extent; len(e) == 4 {
+		geo.BoundingBox = []float64{e[3], e[1], e[0], e[2]}
+	}
+	return geo
+}
+
+// PeliasResult is the GeoJSON FeatureCollection returned by Pelias.
+type PeliasResult struct {
+	Geocoding struct {
+		Query struct {
+			// Lang is the language Pelias used for the names, Defaulted
+			// when it didn't accept the requested one.
+			Lang struct {
+				ISO6393   string `json:"iso6393"`
+				Defaulted bool   `json:"defaulted"`
+			} `json:"lang"`
+		} `json:"query"`
+	} `json:"geocoding"`
+	Features []PeliasFeature `json:"features"`
+}
+
+type PeliasFeature struct {
+	Geometry struct {
+		// Coordinates is lon, lat as in GeoJSON.
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	// BBox is min lon, min lat, max lon, max lat.
+	BBox       []float64 `json:"bbox"`
+	Properties struct {
+		GID         string `json:"gid"`
+		Layer       string `json:"layer"`
+		Source      string `json:"source"`
+		SourceID    string `json:"source_id"`
+		Name        string `json:"name"`
+		CountryCode string `json:"country_code"`
+		Region      string `json:"region"`
+		County      string `json:"county"`
+	} `json:"properties"`
+}
+
+// Candidate returns the country code and administrative areas of the feature.
+func (f PeliasFeature) Candidate() Candidate {
+	candidate := Candidate{CountryCode: f.Properties.CountryCode}
+	for _, area := range []string{f.Properties.Region, f.Properties.County} {
+		if area != "" {
+			candidate.Regions = append(candidate.Regions, area)
+		}
+	}
+	return candidate
+}
+
+// Geo returns the position and extent of the feature, and its OSM object
+// when it comes from OpenStreetMap, where source_id reads "relation/123".
+func (f PeliasFeature) Geo(provider string) *Geo {
+	geo := &Geo{Provider: provider}
+	if c := f.Geometry.Coordinates; len(c) == 2 {
+		geo.Lon, geo.Lat = c[0], c[1]
+	}
+	if b := f.BBox; len(b) == 4 {
+		geo.BoundingBox = []float64{b[1], b[3], b[0], b[2]}
+	}
+	if f.Properties.Source == "openstreetmap" {
+		parts := strings.SplitN(f.Properties.SourceID, "/", 2)
+		if len(parts) == 2 {
+			if id, err := strconv.ParseInt(parts[1], 10, 64); err == nil && OSMType(parts[0]) != "" {
+				geo.OSMType, geo.OSMID = OSMType(parts[0]), id
+			}
+		}
+	}
+	return geo
+}
+
+// OpenCageResponse is the JSON response of the OpenCage geocoding API.
+type OpenCageResponse struct {
+	Results []OpenCageResult `json:"results"`
+}
+
+type OpenCageResult struct {
+	// Components holds the address parts; "_type" names the part the
+	// result stands for, e.g. "city" or "country".
+	Components map[string]interface{} `json:"components"`
+	Geometry   GeoPoint               `json:"geometry"`
+	Bounds     *struct {
+		NorthEast GeoPoint `json:"northeast"`
+		SouthWest GeoPoint `json:"southwest"`
+	} `json:"bounds"`
+	Annotations struct {
+		OSM struct {
+			// EditURL reads https://www.openstreetmap.org/edit?relation=123
+			EditURL string `json:"edit_url"`
+		} `json:"OSM"`
+		Wikidata string `json:"wikidata"`
+	} `json:"annotations"`
+	Confidence int `json:"confidence"`
+}
+
+// GeoPoint is a position in OpenCage responses.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Component returns the string value of an address component.
+func (r OpenCageResult) Component(key string) string {
+	value, _ := r.Components[key].(string)
+	return value
+}
+
+// Type returns the kind of place the result stands for.
+func (r OpenCageResult) Type() string {
+	return r.Component("_type")
+}
+
+// Name returns the name of the place itself, the component named by _type.
+func (r OpenCageResult) Name() string {
+	return r.Component(r.Type())
+}
+
+// Candidate returns the country code and administrative areas of the result.
+func (r OpenCageResult) Candidate() Candidate {
+	candidate := Candidate{CountryCode: r.Component("country_code")}
+	for _, key := range []string{"state", "region", "province", "county", "state_district"} {
+		if area := r.Component(key); area != "" {
+			candidate.Regions = append(candidate.Regions, area)
+		}
+	}
+	return candidate
+}
+
+// OSMRef returns the OSM object named in the OSM annotation, if any.
+func (r OpenCageResult) OSMRef() *OSMRef {
+	u, err := url.Parse(r.Annotations.OSM.EditURL)
+	if err != nil {
+		return nil
+	}
+	query := u.Query()
+	for _, osmType := range []string{OSMNode, OSMWay, OSMRelation} {
+		if id, err := strconv.ParseInt(query.Get(osmType), 10, 64); err == nil {
+			return &OSMRef{Type: osmType, ID: id}
+		}
+	}
+	return nil
+}
+
+// Geo returns the position, bounds, OSM object and Wikidata item of the
+// result.
+func (r OpenCageResult) Geo(provider string) *Geo {
+	geo := &Geo{Provider: provider, Lat: r.Geometry.Lat, Lon: r.Geometry.Lng, Wikidata: r.Annotations.Wikidata}
+	if r.Bounds != nil {
+		geo.BoundingBox = []float64{r.Bounds.SouthWest.Lat, r.Bounds.NorthEast.Lat, r.Bounds.SouthWest.Lng, r.Bounds.NorthEast.Lng}
+	}
+	if ref := r.OSMRef(); ref != nil {
+		geo.OSMType, geo.OSMID = ref.Type, ref.ID
+	}
+	return geo
+}
+
 type Model interface {
 	Type() string
 	Id() int
@@ -55,3 +420,18 @@ func (m City) Id() int {
 func (m City) Type() string {
 	return "city"
 }
+
+func (m Country) String() string {
+	return fmt.Sprintf("ID: %d, Name: %s, NameEN: %s", m.ID, deref(m.Name), deref(m.NameEN))
+}
+
+func (m City) String() string {
+	return fmt.Sprintf("ID: %d, Name: %s, NameNational: %s", m.ID, deref(m.Name), deref(m.NameNational))
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
@@ -0,0 +1,50 @@
+package models
+
+// CitiesQuery selects cities together with the code and name of their
+// country, the OSM object and Wikidata item they were linked to and their
+// coordinates, wrapped so that callers can filter on the city ID.
+const CitiesQuery = "SELECT id, name, name_national, country_code, country_name, region, osm_type, osm_id, wikidata, lat, lon FROM (" +
+	"SELECT ci.id, ci.name, ci.name_national, co.code AS country_code, co.name_en AS country_name, ci.region, g.osm_type, g.osm_id, w.wikidata, ci.lat, ci.lon " +
+	"FROM cities ci LEFT JOIN countries co ON co.id = ci.country_id " +
+	"LEFT JOIN (SELECT DISTINCT ON (city_id) city_id, osm_type, osm_id FROM cities_geo_attributes " +
+	"WHERE osm_id IS NOT NULL ORDER BY city_id, provider <> 'nominatim') g ON g.city_id = ci.id " +
+	"LEFT JOIN (SELECT DISTINCT ON (city_id) city_id, wikidata FROM cities_geo_attributes " +
+	"WHERE wikidata IS NOT NULL ORDER BY city_id, provider <> 'nominatim') w ON w.city_id = ci.id) cities"
+
+// CountriesQuery selects countries together with the OSM object and the
+// Wikidata item they were linked to, wrapped like CitiesQuery.
+const CountriesQuery = "SELECT id, name, name_en, osm_type, osm_id, wikidata FROM (" +
+	"SELECT co.id, co.name, co.name_en, g.osm_type, g.osm_id, w.wikidata FROM countries co " +
+	"LEFT JOIN (SELECT DISTINCT ON (country_id) country_id, osm_type, osm_id FROM countries_geo_attributes " +
+	"WHERE osm_id IS NOT NULL ORDER BY country_id, provider <> 'nominatim') g ON g.country_id = co.id " +
+	"LEFT JOIN (SELECT DISTINCT ON (country_id) country_id, wikidata FROM countries_geo_attributes " +
+	"WHERE wikidata IS NOT NULL ORDER BY country_id, provider <> 'nominatim') w ON w.country_id = co.id) countries"
+
+// Scanner is implemented by *sql.Row and *sql.Rows.
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ScanCity reads a row selected by CitiesQuery.
+func ScanCity(row Scanner) (City, error) {
+	var (
+		city    City
+		osmType *string
+		osmID   *int64
+	)
+	err := row.Scan(&city.ID, &city.Name, &city.NameNational, &city.CountryCode, &city.CountryName, &city.Region, &osmType, &osmID, &city.Wikidata, &city.Lat, &city.Lon)
+	city.OSM = NewOSMRef(osmType, osmID)
+	return city, err
+}
+
+// ScanCountry reads a row selected by CountriesQuery.
+func ScanCountry(row Scanner) (Country, error) {
+	var (
+		country Country
+		osmType *string
+		osmID   *int64
+	)
+	err := row.Scan(&country.ID, &country.Name, &country.NameEN, &osmType, &osmID, &country.Wikidata)
+	country.OSM = NewOSMRef(osmType, osmID)
+	return country, err
+}
@@ -0,0 +1,132 @@
+package models
+
+import "strings"
+
+// Kinds of alternative names, named after the OSM tags they come from.
+const (
+	KindName     = "name"
+	KindOfficial = "official_name"
+	KindShort    = "short_name"
+	KindAlt      = "alt_name"
+	KindOld      = "old_name"
+	KindLocal    = "loc_name"
+)
+
+// AlternativeKinds lists the alternative name tags in ranking order.
+var AlternativeKinds = []string{KindOfficial, KindShort, KindAlt, KindOld, KindLocal}
+
+// Alternative is a candidate name for a locale together with the providers
+// which returned it.
+type Alternative struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind,omitempty"`
+	Providers  []string `json:"providers"`
+	Confidence float64  `json:"confidence,omitempty"`
+}
+
+// Result is the normalized outcome of a provider lookup: localized names
+// keyed by locale plus the international name when the provider has one.
+// Sources records the provider of names which didn't come from Provider.
+// Confidence is filled when several providers were compared, Alternatives
+// holds the other candidate names per locale in ranking order. Geo is the
+// position and OSM object of the place when the provider returned them.
+type Result struct {
+	Provider     string
+	Names        map[string]string
+	IntName      *string
+	Sources      map[string]string
+	Confidence   map[string]float64
+	Alternatives map[string][]Alternative
+	Geo          *Geo
+}
+
+func NewResult(provider string) *Result {
+	return &Result{
+		Provider:     provider,
+		Names:        map[string]string{},
+		Sources:      map[string]string{},
+		Confidence:   map[string]float64{},
+		Alternatives: map[string][]Alternative{},
+	}
+}
+
+// Source returns the provider which supplied the name for locale.
+func (r *Result) Source(locale string) string {
+	if source, ok := r.Sources[locale]; ok {
+		return source
+	}
+	return r.Provider
+}
+
+// AddAlternative appends alt to the alternatives of locale unless it
+// repeats the chosen name. Repeated alternatives only gain providers.
+func (r *Result) AddAlternative(locale string, alt Alternative) {
+	key := NormalizeName(alt.Name)
+	if key == "" || key == NormalizeName(r.Names[locale]) {
+		return
+	}
+	for i, existing := range r.Alternatives[locale] {
+		if NormalizeName(existing.Name) != key {
+			continue
+		}
+		for _, p := range alt.Providers {
+			if !contains(existing.Providers, p) {
+				r.Alternatives[locale][i].Providers = append(r.Alternatives[locale][i].Providers, p)
+			}
+		}
+		return
+	}
+	r.Alternatives[locale] = append(r.Alternatives[locale], alt)
+}
+
+// Merge adds names for locales r doesn't have yet, remembering where they
+// came from, all alternatives of other and its Geo, or Wikidata item, when r
+// has none.
+func (r *Result) Merge(other *Result) {
+	for locale, name := range other.Names {
+		if _, ok := r.Names[locale]; ok {
+			r.AddAlternative(locale, Alternative{Name: name, Kind: KindName, Providers: []string{other.Source(locale)}})
+			continue
+		}
+		r.Names[locale] = name
+		if source := other.Source(locale); source != r.Provider {
+			r.Sources[locale] = source
+		}
+	}
+	for locale, alternatives := range other.Alternatives {
+		for _, alt := range alternatives {
+			r.AddAlternative(locale, alt)
+		}
+	}
+	if r.IntName == nil {
+		r.IntName = other.IntName
+	}
+	if r.Geo == nil {
+		r.Geo = other.Geo
+	} else if r.Geo.Wikidata == "" && other.Geo != nil {
+		r.Geo.Wikidata = other.Geo.Wikidata
+	}
+}
+
+// Wikidata returns the Q-ID of the Wikidata item the result was linked to.
+func (r *Result) Wikidata() string {
+	if r.Geo == nil {
+		return ""
+	}
+	return r.Geo.Wikidata
+}
+
+// NormalizeName folds case and whitespace so that spellings differing only
+// in those compare equal.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
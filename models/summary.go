@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// BreakerState is a snapshot of a provider's circuit breaker.
+type BreakerState struct {
+	Provider string
+	State    string
+	Trips    int
+}
+
+// Summary describes the outcome of a single localization run.
+type Summary struct {
+	Provider  string
+	Entity    string
+	Processed int
+	Succeeded int
+	Failed    int
+	Breakers  []BreakerState
+	Started   time.Time
+	Finished  time.Time
+}
+
+func NewSummary(provider, entity string) *Summary {
+	return &Summary{
+		Provider: provider,
+		Entity:   entity,
+		Started:  time.Now(),
+	}
+}
+
+func (s *Summary) Done() *Summary {
+	s.Finished = time.Now()
+	return s
+}
+
+func (s *Summary) Duration() time.Duration {
+	if s.Finished.IsZero() {
+		return time.Since(s.Started)
+	}
+	return s.Finished.Sub(s.Started)
+}
+
+func (s *Summary) String() string {
+	str := fmt.Sprintf(
+		"%s/%s: processed %d, succeeded %d, failed %d in %s",
+		s.Provider, s.Entity, s.Processed, s.Succeeded, s.Failed, s.Duration().Round(time.Millisecond),
+	)
+	for _, b := range s.Breakers {
+		str += fmt.Sprintf("; breaker %s %s, tripped %d times", b.Provider, b.State, b.Trips)
+	}
+	return str
+}
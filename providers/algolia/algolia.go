@@ -2,71 +2,97 @@ package algolia
 
 import (
 	"bytes"
-	"database/sql"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
-	"github.com/lensgolda/geocapture/logfile"
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/locales"
 	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/ratelimit"
 	"github.com/lensgolda/geocapture/settings"
 )
 
 const (
-	backupHost1        = "https://places-1.algolianet.com"
-	backupHost2        = "https://places-2.algolianet.com"
-	backupHost3        = "https://places-3.algolianet.com"
 	providerFailedFile = "algolia.failed"
 	providerName       = "algolia"
 )
 
-var (
-	allowedLocales = map[string]string{
-		"ru": "ru",
-		"en": "en",
-		"kk": "kk",
-		"uk": "uk",
-	}
-	query = map[string]string{
-		"type": "city",
-	}
-	data map[string]interface{}
-	city models.City
-)
-
 type Algolia struct {
 	Name           string
 	FailedFileName string
-	RequestTimeout time.Duration
-	Client         *http.Client
+	Client         *httpclient.Client
+	// AllNames keeps every name of every hit instead of only the first
+	// name per locale of the first hit.
+	AllNames bool
 }
 
-func NewProvider() *Algolia {
+var _ interfaces.Provider = (*Algolia)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name:     providerName,
+		Settings: []string{"ALGOLIA_APP_ID", "ALGOLIA_API_KEY"},
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
+}
+
+func NewProvider() (*Algolia, error) {
+	client, err := httpclient.New(
+		30*time.Second,
+		ratelimit.New(settings.Config.Algolia.Rate, settings.Config.Algolia.Burst),
+		settings.Config.Algolia.Hosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Algolia{
 		Name:           providerName,
 		FailedFileName: providerFailedFile,
-		RequestTimeout: time.Millisecond * 50,
-		Client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+		Client:         client,
+		AllNames:       settings.Config.App.AllNames,
+	}, nil
 }
 
-func (alg *Algolia) CreateCitiesRequest(city models.City) (*http.Request, error) {
+func (alg *Algolia) CreateRequest(ctx context.Context, model models.Model) (*http.Request, error) {
+	query := map[string]interface{}{
+		"type": model.Type(),
+	}
 
-	if city.Name != nil {
-		query["query"] = *city.Name
-	} else {
-		if city.NameNational != nil {
-			query["query"] = *city.NameNational
+	switch m := model.(type) {
+	case models.City:
+		if m.Name != nil {
+			query["query"] = *m.Name
 		} else {
-			return nil, errors.New("both names from cities table are NULL")
+			if m.NameNational != nil {
+				query["query"] = *m.NameNational
+			} else {
+				return nil, fmt.Errorf("%w: both names from cities table are NULL", models.ErrNoName)
+			}
 		}
+		if m.CountryCode != nil && *m.CountryCode != "" {
+			query["countries"] = []string{strings.ToLower(*m.CountryCode)}
+		}
+	case models.Country:
+		if m.Name != nil {
+			query["query"] = *m.Name
+		} else {
+			if m.NameEN != nil {
+				query["query"] = *m.NameEN
+			} else {
+				return nil, fmt.Errorf("%w: both names from countries table are NULL", models.ErrNoName)
+			}
+		}
+	default:
+		return nil, models.ErrWrongModel
 	}
 
 	requestBody, err := json.Marshal(query)
@@ -74,7 +100,7 @@ func (alg *Algolia) CreateCitiesRequest(city models.City) (*http.Request, error)
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", settings.Config.Algolia.URL, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", settings.Config.Algolia.URL, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, err
 	}
@@ -87,158 +113,94 @@ func (alg *Algolia) CreateCitiesRequest(city models.City) (*http.Request, error)
 	return req, nil
 }
 
-func (alg *Algolia) ParseCitiesResponse(resp *http.Response) (map[string]interface{}, error) {
-	bytesBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+func (alg *Algolia) ParseResponse(bytesBody []byte) ([]models.Hit, error) {
+	var data models.AlgResult
 	if err := json.Unmarshal(bytesBody, &data); err != nil {
 		return nil, err
 	}
 
-	if len(data) == 0 {
-		return nil, errors.New("response data have zero length")
+	if len(data.Hit) == 0 {
+		return nil, models.ErrEmptyResult
 	}
 
-	if records, ok := data["hits"]; ok {
-		if hits, ok := records.([]interface{}); ok {
-			if len(hits) != 0 {
-				if firstHit, ok := hits[0].(map[string]interface{}); ok {
-					if localeNames, ok := firstHit["locale_names"].(map[string]interface{}); ok {
-						return localeNames, nil
-					}
-				}
-			}
-		}
+	if data.Hit[0].LocaleNames == nil {
+		return nil, models.ErrUnexpectedResponse
 	}
-
-	return nil, errors.New("nested data error, see data nested types and values")
+	return data.Hit, nil
 }
 
-func (alg Algolia) ProcessCitiesData(db *sql.DB, data interface{}, city models.City) error {
-	if data, ok := data.(map[string]interface{}); ok {
-		var (
-			stmt *sql.Stmt
-			err  error
-		)
-		defer func() {
-			_ = stmt.Close()
-		}()
-
-		for k, v := range data {
-			if locale, ok := allowedLocales[k]; ok {
-				stmt, err = db.Prepare("INSERT INTO cities_translations(city_id, locale, name, int_name) VALUES ($1, $2, $3, $4)")
-				if err != nil {
-					return err
-				}
+// ProcessData picks the first name of every locale from the locale_names of
+// the hit matching model best, skipping the "default" entry. With AllNames
+// the remaining names of all matching hits become alternatives.
+func (alg *Algolia) ProcessData(hits []models.Hit, model models.Model) (*models.Result, error) {
+	candidates := make([]models.Candidate, 0, len(hits))
+	for _, hit := range hits {
+		candidates = append(candidates, hit.Candidate())
+	}
+	ranked, err := models.Rank(model, candidates)
+	if err != nil {
+		return nil, err
+	}
 
-				if names, ok := v.([]interface{}); ok {
-					if len(names) == 0 {
-						continue
-					}
-					if name, ok := names[0].(string); ok {
-						if _, err = stmt.Exec(city.ID, locale, name, city.NameNational); err != nil {
-							return err
-						}
-						log.Printf("Insert OK: CityID = %d, locale = %s, name = %s, int_name = %v\n", city.ID, locale, name, city.NameNational)
-					}
-				}
-			}
+	result := models.NewResult(alg.Name)
+	switch m := model.(type) {
+	case models.City:
+		result.IntName = m.NameNational
+	case models.Country:
+		result.IntName = m.NameEN
+	}
+
+	result.Geo = hits[ranked[0]].Geo(alg.Name)
+	for k, names := range hits[ranked[0]].LocaleNames {
+		locale := locales.Canonical(k)
+		if !locales.Valid(locale) || len(names) == 0 {
+			continue
 		}
-		return nil
+		result.Names[locale] = names[0]
 	}
-	return errors.New("data is not of type map[string]interface{}")
-}
 
-func (alg *Algolia) RetryBackupHosts(client http.Client, r *http.Request) (*http.Response, error) {
-
-	r.URL.Host = backupHost1
-	respB1, errB1 := client.Do(r)
-	if errB1 != nil {
-		log.Println("backup host1 unreachable: ", errB1.Error())
-		r.URL.Host = backupHost2
-		respB2, errB2 := client.Do(r)
-		if errB2 != nil {
-			log.Println("backup host2 unreachable: ", errB2.Error())
-			r.URL.Host = backupHost3
-			respB3, errB3 := client.Do(r)
-			if errB3 != nil {
-				log.Println("backup host3 unreachable: ", errB3.Error())
-				return nil, errB3
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	if alg.AllNames {
+		for _, i := range ranked {
+			for k, names := range hits[i].LocaleNames {
+				locale := locales.Canonical(k)
+				if !locales.Valid(locale) {
+					continue
+				}
+				for _, name := range names {
+					result.AddAlternative(locale, models.Alternative{Name: name, Kind: models.KindName, Providers: []string{alg.Name}})
+				}
 			}
-			return respB3, nil
 		}
-		return respB2, nil
 	}
-	return respB1, nil
+	return result, nil
 }
 
 func (alg *Algolia) FailedFile() string {
 	return alg.FailedFileName
 }
 
-func (alg *Algolia) ProcessCities(db *sql.DB) {
-	f, err := os.OpenFile(alg.FailedFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func (alg *Algolia) ProviderName() string {
+	return alg.Name
+}
+
+func (alg *Algolia) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	req, err := alg.CreateRequest(ctx, model)
 	if err != nil {
-		log.Fatal("Failed to open file: ", err)
+		return nil, err
 	}
-	defer func() {
-		_ = f.Close()
-	}()
 
-	rowsAll, err := db.Query("SELECT id, name, name_national FROM cities ORDER BY id")
+	body, err := alg.Client.Do(req)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer func() {
-		_ = rowsAll.Close()
-	}()
 
-	var (
-		counter uint = 0
-		resp    *http.Response
-	)
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	for rowsAll.Next() {
-		counter += 1
-		if err := rowsAll.Scan(&city.ID, &city.Name, &city.NameNational); err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err)
-			continue
-		}
-		fmt.Printf("%d >>> CityID: %d Name: %v\n", counter, city.ID, city.Name)
-
-		req, err := alg.CreateCitiesRequest(city)
-		if err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
-
-		resp, err := alg.Client.Do(req)
-		if err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
-
-		data, err := alg.ParseCitiesResponse(resp)
-		if err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
-
-		if err := alg.ProcessCitiesData(db, data, city); err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
-
-		time.Sleep(alg.RequestTimeout)
+	hits, err := alg.ParseResponse(body)
+	if err != nil {
+		return nil, err
 	}
+
+	return alg.ProcessData(hits, model)
 }
@@ -0,0 +1,104 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/models"
+)
+
+const providerFailedFile = "chain.failed"
+
+// Chain tries its providers in order for every record. Without Locales the
+// first successful lookup wins; with Locales the next providers are asked
+// for the locales still missing and their names are merged in, each one
+// keeping track of the provider that supplied it.
+type Chain struct {
+	Name           string
+	FailedFileName string
+	Providers      []interfaces.Provider
+	Locales        []string
+}
+
+var (
+	_ interfaces.Provider        = (*Chain)(nil)
+	_ interfaces.BreakerReporter = (*Chain)(nil)
+)
+
+func New(providers ...interfaces.Provider) *Chain {
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.ProviderName()
+	}
+	return &Chain{
+		Name:           "chain:" + strings.Join(names, ","),
+		FailedFileName: providerFailedFile,
+		Providers:      providers,
+	}
+}
+
+func (c *Chain) ProviderName() string {
+	return c.Name
+}
+
+func (c *Chain) FailedFile() string {
+	return c.FailedFileName
+}
+
+func (c *Chain) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	var (
+		merged *models.Result
+		errs   []string
+		last   error
+	)
+	for _, p := range c.Providers {
+		result, err := p.Lookup(ctx, model)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, p.ProviderName()+": "+err.Error())
+			last = err
+			continue
+		}
+
+		if merged == nil {
+			merged = models.NewResult(result.Provider)
+		}
+		merged.Merge(result)
+		if c.complete(merged) {
+			break
+		}
+		// let the next providers, such as wikidata, use the item found
+		if id := merged.Wikidata(); id != "" && models.WikidataOf(model) == "" {
+			model = models.WithWikidata(model, id)
+		}
+	}
+
+	if merged == nil {
+		return nil, fmt.Errorf("all providers failed (%s): %w", strings.Join(errs, "; "), last)
+	}
+	return merged, nil
+}
+
+// complete reports whether every wanted locale has a name.
+func (c *Chain) complete(result *models.Result) bool {
+	for _, locale := range c.Locales {
+		if _, ok := result.Names[locale]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Chain) BreakerStates() []models.BreakerState {
+	var states []models.BreakerState
+	for _, p := range c.Providers {
+		if r, ok := p.(interfaces.BreakerReporter); ok {
+			states = append(states, r.BreakerStates()...)
+		}
+	}
+	return states
+}
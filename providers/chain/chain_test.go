@@ -0,0 +1,158 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+type stubProvider struct {
+	name     string
+	names    map[string]string
+	wikidata string
+	err      error
+
+	calls int
+	seen  models.Model
+}
+
+func (s *stubProvider) ProviderName() string { return s.name }
+func (s *stubProvider) FailedFile() string   { return s.name + ".failed" }
+
+func (s *stubProvider) Lookup(_ context.Context, model models.Model) (*models.Result, error) {
+	s.calls += 1
+	s.seen = model
+	if s.err != nil {
+		return nil, s.err
+	}
+	result := models.NewResult(s.name)
+	for locale, name := range s.names {
+		result.Names[locale] = name
+	}
+	if s.wikidata != "" {
+		result.Geo = &models.Geo{Provider: s.name, Wikidata: s.wikidata}
+	}
+	return result, nil
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name      string
+		locales   []string
+		providers []*stubProvider
+		names     map[string]string
+		sources   map[string]string
+		calls     []int
+	}{
+		{
+			name: "first success wins without locales",
+			providers: []*stubProvider{
+				{name: "a", names: map[string]string{"ru": "Алматы"}},
+				{name: "b", names: map[string]string{"en": "Almaty"}},
+			},
+			names:   map[string]string{"ru": "Алматы"},
+			sources: map[string]string{},
+			calls:   []int{1, 0},
+		},
+		{
+			name:    "failures are skipped",
+			locales: []string{"en"},
+			providers: []*stubProvider{
+				{name: "a", err: models.ErrEmptyResult},
+				{name: "b", names: map[string]string{"en": "Almaty"}},
+			},
+			names:   map[string]string{"en": "Almaty"},
+			sources: map[string]string{},
+			calls:   []int{1, 1},
+		},
+		{
+			name:    "missing locales merged until complete",
+			locales: []string{"ru", "en"},
+			providers: []*stubProvider{
+				{name: "a", names: map[string]string{"ru": "Алматы"}},
+				{name: "b", names: map[string]string{"ru": "Алма-Ата", "en": "Almaty"}},
+				{name: "c", names: map[string]string{"en": "Alma-Ata"}},
+			},
+			names:   map[string]string{"ru": "Алматы", "en": "Almaty"},
+			sources: map[string]string{"en": "b"},
+			calls:   []int{1, 1, 0},
+		},
+		{
+			name:    "incomplete after all providers",
+			locales: []string{"ru", "kk"},
+			providers: []*stubProvider{
+				{name: "a", names: map[string]string{"ru": "Алматы"}},
+				{name: "b", err: models.ErrNoLocale},
+			},
+			names:   map[string]string{"ru": "Алматы"},
+			sources: map[string]string{},
+			calls:   []int{1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			for _, p := range tt.providers {
+				c.Providers = append(c.Providers, p)
+			}
+			c.Locales = tt.locales
+
+			result, err := c.Lookup(context.Background(), models.City{ID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Names, tt.names) || !reflect.DeepEqual(result.Sources, tt.sources) {
+				t.Errorf("names %v from %v, want %v from %v", result.Names, result.Sources, tt.names, tt.sources)
+			}
+			for i, p := range tt.providers {
+				if p.calls != tt.calls[i] {
+					t.Errorf("provider %s called %d times, want %d", p.name, p.calls, tt.calls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLookupConflictingName(t *testing.T) {
+	c := New(
+		&stubProvider{name: "a", names: map[string]string{"ru": "Алматы"}},
+		&stubProvider{name: "b", names: map[string]string{"ru": "Алма-Ата", "en": "Almaty"}},
+	)
+	c.Locales = []string{"ru", "en"}
+
+	result, err := c.Lookup(context.Background(), models.City{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Alternative{{Name: "Алма-Ата", Kind: models.KindName, Providers: []string{"b"}}}
+	if !reflect.DeepEqual(result.Alternatives["ru"], want) {
+		t.Errorf("ru alternatives %v, want %v", result.Alternatives["ru"], want)
+	}
+}
+
+func TestLookupAllFailed(t *testing.T) {
+	c := New(
+		&stubProvider{name: "a", err: models.ErrEmptyResult},
+		&stubProvider{name: "b", err: models.ErrNoMatch},
+	)
+
+	if _, err := c.Lookup(context.Background(), models.City{ID: 1}); !errors.Is(err, models.ErrNoMatch) {
+		t.Errorf("Lookup() = %v, want it to wrap %v", err, models.ErrNoMatch)
+	}
+}
+
+func TestLookupPassesWikidata(t *testing.T) {
+	next := &stubProvider{name: "wikidata", names: map[string]string{"kk": "Алматы"}}
+	c := New(&stubProvider{name: "a", names: map[string]string{"ru": "Алматы"}, wikidata: "Q35493"}, next)
+	c.Locales = []string{"ru", "kk"}
+
+	if _, err := c.Lookup(context.Background(), models.City{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := models.WikidataOf(next.seen); got != "Q35493" {
+		t.Errorf("next provider saw Q-ID %q, want Q35493", got)
+	}
+}
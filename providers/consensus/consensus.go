@@ -0,0 +1,168 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/models"
+)
+
+const providerFailedFile = "consensus.failed"
+
+// Consensus asks all of its providers about every record and, per locale,
+// picks the name most providers agree on. Confidence is the share of
+// answering providers which returned the winning name; the other names are
+// kept as alternatives.
+type Consensus struct {
+	Name           string
+	FailedFileName string
+	Providers      []interfaces.Provider
+}
+
+var (
+	_ interfaces.Provider        = (*Consensus)(nil)
+	_ interfaces.BreakerReporter = (*Consensus)(nil)
+)
+
+func New(providers ...interfaces.Provider) *Consensus {
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.ProviderName()
+	}
+	return &Consensus{
+		Name:           "consensus:" + strings.Join(names, ","),
+		FailedFileName: providerFailedFile,
+		Providers:      providers,
+	}
+}
+
+func (c *Consensus) ProviderName() string {
+	return c.Name
+}
+
+func (c *Consensus) FailedFile() string {
+	return c.FailedFileName
+}
+
+func (c *Consensus) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	results := make([]*models.Result, len(c.Providers))
+	errs := make([]error, len(c.Providers))
+
+	var wg sync.WaitGroup
+	for i, p := range c.Providers {
+		wg.Add(1)
+		go func(i int, p interfaces.Provider) {
+			defer wg.Done()
+			results[i], errs[i] = p.Lookup(ctx, model)
+		}(i, p)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		answered []*models.Result
+		failures []string
+		last     error
+	)
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, c.Providers[i].ProviderName()+": "+err.Error())
+			last = err
+			continue
+		}
+		answered = append(answered, results[i])
+	}
+	if len(answered) == 0 {
+		return nil, fmt.Errorf("all providers failed (%s): %w", strings.Join(failures, "; "), last)
+	}
+
+	return vote(c.Name, answered), nil
+}
+
+// candidate collects the providers which returned the same name, compared
+// case and whitespace insensitively.
+type candidate struct {
+	name      string
+	providers []string
+	order     int
+}
+
+func vote(name string, answered []*models.Result) *models.Result {
+	result := models.NewResult(name)
+
+	byLocale := map[string]map[string]*candidate{}
+	for _, r := range answered {
+		if result.IntName == nil {
+			result.IntName = r.IntName
+		}
+		if result.Geo == nil {
+			result.Geo = r.Geo
+		}
+		for locale, n := range r.Names {
+			if byLocale[locale] == nil {
+				byLocale[locale] = map[string]*candidate{}
+			}
+			key := models.NormalizeName(n)
+			c, ok := byLocale[locale][key]
+			if !ok {
+				c = &candidate{name: n, order: len(byLocale[locale])}
+				byLocale[locale][key] = c
+			}
+			c.providers = append(c.providers, r.Source(locale))
+		}
+	}
+
+	total := float64(len(answered))
+	for locale, candidates := range byLocale {
+		ranked := make([]*candidate, 0, len(candidates))
+		for _, c := range candidates {
+			ranked = append(ranked, c)
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			if len(ranked[i].providers) != len(ranked[j].providers) {
+				return len(ranked[i].providers) > len(ranked[j].providers)
+			}
+			return ranked[i].order < ranked[j].order
+		})
+
+		winner := ranked[0]
+		result.Names[locale] = winner.name
+		result.Sources[locale] = winner.providers[0]
+		result.Confidence[locale] = float64(len(winner.providers)) / total
+		for _, c := range ranked[1:] {
+			result.AddAlternative(locale, models.Alternative{
+				Name:       c.name,
+				Kind:       models.KindName,
+				Providers:  c.providers,
+				Confidence: float64(len(c.providers)) / total,
+			})
+		}
+	}
+
+	// alternatives reported by the providers themselves rank after the
+	// names they voted on
+	for _, r := range answered {
+		for locale, alternatives := range r.Alternatives {
+			for _, alt := range alternatives {
+				result.AddAlternative(locale, alt)
+			}
+		}
+	}
+	return result
+}
+
+func (c *Consensus) BreakerStates() []models.BreakerState {
+	var states []models.BreakerState
+	for _, p := range c.Providers {
+		if r, ok := p.(interfaces.BreakerReporter); ok {
+			states = append(states, r.BreakerStates()...)
+		}
+	}
+	return states
+}
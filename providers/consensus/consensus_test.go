@@ -0,0 +1,151 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+type stubProvider struct {
+	name         string
+	names        map[string]string
+	alternatives map[string][]models.Alternative
+	err          error
+}
+
+func (s stubProvider) ProviderName() string { return s.name }
+func (s stubProvider) FailedFile() string   { return s.name + ".failed" }
+
+func (s stubProvider) Lookup(context.Context, models.Model) (*models.Result, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	result := models.NewResult(s.name)
+	for locale, name := range s.names {
+		result.Names[locale] = name
+	}
+	for locale, alternatives := range s.alternatives {
+		result.Alternatives[locale] = alternatives
+	}
+	return result, nil
+}
+
+func names(ru string) map[string]string {
+	return map[string]string{"ru": ru}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name         string
+		providers    []stubProvider
+		winner       string
+		source       string
+		confidence   float64
+		alternatives []models.Alternative
+	}{
+		{
+			name: "majority ignoring case and whitespace",
+			providers: []stubProvider{
+				{name: "a", names: names("Алма-Ата")},
+				{name: "b", names: names("Алматы")},
+				{name: "c", names: names("  алматы ")},
+			},
+			winner:     "Алматы",
+			source:     "b",
+			confidence: 2.0 / 3,
+			alternatives: []models.Alternative{
+				{Name: "Алма-Ата", Kind: models.KindName, Providers: []string{"a"}, Confidence: 1.0 / 3},
+			},
+		},
+		{
+			name: "tie goes to the first provider",
+			providers: []stubProvider{
+				{name: "a", names: names("Алматы")},
+				{name: "b", names: names("Алма-Ата")},
+			},
+			winner:     "Алматы",
+			source:     "a",
+			confidence: 0.5,
+			alternatives: []models.Alternative{
+				{Name: "Алма-Ата", Kind: models.KindName, Providers: []string{"b"}, Confidence: 0.5},
+			},
+		},
+		{
+			name: "share of answering providers",
+			providers: []stubProvider{
+				{name: "a", err: models.ErrEmptyResult},
+				{name: "b", names: names("Алматы")},
+				{name: "c", names: names("Алматы")},
+			},
+			winner:     "Алматы",
+			source:     "b",
+			confidence: 1,
+		},
+		{
+			name: "own alternatives rank after voted names",
+			providers: []stubProvider{
+				{name: "a", names: names("Алматы"), alternatives: map[string][]models.Alternative{
+					"ru": {{Name: "Верный", Kind: models.KindOld, Providers: []string{"a"}}},
+				}},
+				{name: "b", names: names("Алма-Ата")},
+				{name: "c", names: names("Алматы")},
+			},
+			winner:     "Алматы",
+			source:     "a",
+			confidence: 2.0 / 3,
+			alternatives: []models.Alternative{
+				{Name: "Алма-Ата", Kind: models.KindName, Providers: []string{"b"}, Confidence: 1.0 / 3},
+				{Name: "Верный", Kind: models.KindOld, Providers: []string{"a"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			for _, p := range tt.providers {
+				c.Providers = append(c.Providers, p)
+			}
+
+			result, err := c.Lookup(context.Background(), models.City{ID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Names["ru"] != tt.winner || result.Source("ru") != tt.source || result.Confidence["ru"] != tt.confidence {
+				t.Errorf("%q from %s with confidence %v, want %q from %s with %v",
+					result.Names["ru"], result.Source("ru"), result.Confidence["ru"], tt.winner, tt.source, tt.confidence)
+			}
+			if !reflect.DeepEqual(result.Alternatives["ru"], tt.alternatives) {
+				t.Errorf("alternatives %+v, want %+v", result.Alternatives["ru"], tt.alternatives)
+			}
+		})
+	}
+}
+
+func TestLookupPartialLocales(t *testing.T) {
+	c := New(
+		stubProvider{name: "a", names: map[string]string{"ru": "Алматы", "en": "Almaty"}},
+		stubProvider{name: "b", names: map[string]string{"ru": "Алматы"}},
+	)
+
+	result, err := c.Lookup(context.Background(), models.City{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Confidence["en"]; got != 0.5 {
+		t.Errorf("en confidence %v, want 0.5 as only one of two answering providers has it", got)
+	}
+}
+
+func TestLookupAllFailed(t *testing.T) {
+	c := New(
+		stubProvider{name: "a", err: models.ErrEmptyResult},
+		stubProvider{name: "b", err: models.ErrNoMatch},
+	)
+
+	if _, err := c.Lookup(context.Background(), models.City{ID: 1}); !errors.Is(err, models.ErrNoMatch) {
+		t.Errorf("Lookup() = %v, want it to wrap %v", err, models.ErrNoMatch)
+	}
+}
@@ -0,0 +1,159 @@
+package geonames
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/settings"
+)
+
+const (
+	providerName       = "geonames"
+	providerFailedFile = "geonames.failed"
+)
+
+// Geonames answers lookups from a local GeoNames index, without network
+// access or rate limits.
+type Geonames struct {
+	Name           string
+	FailedFileName string
+	Index          *Index
+	// AllNames keeps the non-preferred, short, colloquial and historic names
+	// as alternatives.
+	AllNames bool
+}
+
+var _ interfaces.Provider = (*Geonames)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name:     providerName,
+		Settings: []string{"GEONAMES_PLACES_FILE", "GEONAMES_ALTERNATE_NAMES_FILE"},
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
+}
+
+func NewProvider() (*Geonames, error) {
+	list := settings.Config.Geonames.Languages
+	if len(list) == 0 {
+		list = settings.Config.App.Locales
+	}
+	languages, err := locales.Parse(list)
+	if err != nil {
+		return nil, fmt.Errorf("GEONAMES_LANGUAGES: %w", err)
+	}
+	if len(languages) == 0 {
+		return nil, errors.New("GEONAMES_LANGUAGES is empty")
+	}
+
+	index, err := Load(
+		settings.Config.Geonames.PlacesFile,
+		settings.Config.Geonames.AlternateNamesFile,
+		languages,
+		settings.Config.Geonames.MinPopulation,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Geonames{
+		Name:           providerName,
+		FailedFileName: providerFailedFile,
+		Index:          index,
+		AllNames:       settings.Config.App.AllNames,
+	}, nil
+}
+
+// find returns the places model may stand for, looked up by each of its
+// names.
+func (geo *Geonames) find(model models.Model) ([]*Place, error) {
+	var (
+		names   []*string
+		country bool
+	)
+	switch m := model.(type) {
+	case models.City:
+		names = []*string{m.Name, m.NameNational}
+	case models.Country:
+		names = []*string{m.Name, m.NameEN}
+		country = true
+	default:
+		return nil, models.ErrWrongModel
+	}
+
+	searched := false
+	for _, name := range names {
+		if name == nil {
+			continue
+		}
+		searched = true
+		if places := geo.Index.Find(*name, country); len(places) != 0 {
+			return places, nil
+		}
+	}
+	if !searched {
+		return nil, fmt.Errorf("%w: both names of %s are NULL", models.ErrNoName, model.Type())
+	}
+	return nil, models.ErrEmptyResult
+}
+
+// Lookup picks the most populous place in the record's country among those
+// known under its name and takes the preferred name per locale.
+func (geo *Geonames) Lookup(_ context.Context, model models.Model) (*models.Result, error) {
+	places, err := geo.find(model)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(places, func(i, j int) bool {
+		return places[i].Population > places[j].Population
+	})
+	candidates := make([]models.Candidate, 0, len(places))
+	for _, p := range places {
+		candidates = append(candidates, models.Candidate{CountryCode: p.CountryCode})
+	}
+	ranked, err := models.Rank(model, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	best := places[ranked[0]]
+	result := models.NewResult(geo.Name)
+	result.IntName = &best.Name
+	result.Geo = &models.Geo{Provider: geo.Name, Lat: best.Lat, Lon: best.Lon, Wikidata: geo.Index.Wikidata(best.ID)}
+	for locale, names := range geo.Index.Names(best.ID) {
+		for _, name := range names {
+			if name.Historic || name.Colloquial {
+				continue
+			}
+			result.Names[locale] = name.Name
+			break
+		}
+		if !geo.AllNames {
+			continue
+		}
+		for _, name := range names {
+			result.AddAlternative(locale, models.Alternative{Name: name.Name, Kind: name.Kind(), Providers: []string{geo.Name}})
+		}
+	}
+
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	return result, nil
+}
+
+func (geo *Geonames) ProviderName() string {
+	return geo.Name
+}
+
+func (geo *Geonames) FailedFile() string {
+	return geo.FailedFileName
+}
@@ -0,0 +1,242 @@
+package geonames
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Column counts of the GeoNames dump formats, see
+// https://download.geonames.org/export/dump/readme.txt
+const (
+	placeColumns         = 19
+	alternateNameColumns = 8
+)
+
+// Place is a row of allCountries.txt (or citiesNNN.txt) kept in the index.
+type Place struct {
+	ID          int
+	Name        string
+	Lat         float64
+	Lon         float64
+	CountryCode string
+	Population  int64
+	Country     bool
+}
+
+// AlternateName is a row of alternateNamesV2.txt in one of the indexed
+// languages.
+type AlternateName struct {
+	Name       string
+	Preferred  bool
+	Short      bool
+	Colloquial bool
+	Historic   bool
+}
+
+// Kind maps the flags of the alternate name to an alternative kind.
+func (a AlternateName) Kind() string {
+	switch {
+	case a.Historic:
+		return models.KindOld
+	case a.Short:
+		return models.KindShort
+	case a.Colloquial:
+		return models.KindAlt
+	}
+	return models.KindName
+}
+
+// Index holds the populated places and countries of a GeoNames dump, their
+// names for searching and their alternate names per locale in the wanted
+// languages.
+type Index struct {
+	places map[int]*Place
+	// byName maps normalized names, ASCII names and alternate names of the
+	// places file, and the indexed alternate names, to place IDs.
+	byName map[string][]int
+	// names holds the alternate names of every place per locale, preferred
+	// names first.
+	names map[int]map[string][]AlternateName
+	// wikidata maps place IDs to the Q-ID of their Wikidata item.
+	wikidata map[int]string
+}
+
+// Load indexes the places file, skipping populated places below
+// minPopulation, and then the alternate names of the indexed places in
+// languages. The full dump holds names in hundreds of languages, keeping
+// them all takes many gigabytes.
+func Load(placesFile, alternateNamesFile string, languages []string, minPopulation int64) (*Index, error) {
+	index := &Index{
+		places:   map[int]*Place{},
+		byName:   map[string][]int{},
+		names:    map[int]map[string][]AlternateName{},
+		wikidata: map[int]string{},
+	}
+	if err := index.loadPlaces(placesFile, minPopulation); err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, locale := range languages {
+		wanted[locale] = true
+	}
+	if err := index.loadAlternateNames(alternateNamesFile, wanted); err != nil {
+		return nil, err
+	}
+	for _, byLocale := range index.names {
+		for _, names := range byLocale {
+			sort.SliceStable(names, func(i, j int) bool {
+				return names[i].Preferred && !names[j].Preferred
+			})
+		}
+	}
+	log.Printf("GeoNames index: %d places, %d names\n", len(index.places), len(index.byName))
+	return index, nil
+}
+
+func scanLines(fileName string, columns int, line func(fields []string) error) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	// the alternatenames column of large places runs over the default limit
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n += 1
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < columns {
+			return fmt.Errorf("%s:%d: expected %d columns, got %d", fileName, n, columns, len(fields))
+		}
+		if err := line(fields); err != nil {
+			return fmt.Errorf("%s:%d: %w", fileName, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (index *Index) loadPlaces(fileName string, minPopulation int64) error {
+	return scanLines(fileName, placeColumns, func(fields []string) error {
+		featureClass, featureCode := fields[6], fields[7]
+		country := featureClass == "A" && strings.HasPrefix(featureCode, "PCL")
+		if featureClass != "P" && !country {
+			return nil
+		}
+		population, _ := strconv.ParseInt(fields[14], 10, 64)
+		if !country && population < minPopulation {
+			return nil
+		}
+
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return err
+		}
+		lat, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return err
+		}
+		lon, err := strconv.ParseFloat(fields[5], 64)
+		if err != nil {
+			return err
+		}
+		index.places[id] = &Place{
+			ID:          id,
+			Name:        fields[1],
+			Lat:         lat,
+			Lon:         lon,
+			CountryCode: fields[8],
+			Population:  population,
+			Country:     country,
+		}
+
+		index.addName(fields[1], id)
+		index.addName(fields[2], id)
+		for _, name := range strings.Split(fields[3], ",") {
+			index.addName(name, id)
+		}
+		return nil
+	})
+}
+
+func (index *Index) addName(name string, id int) {
+	key := models.NormalizeName(name)
+	if key == "" {
+		return
+	}
+	ids := index.byName[key]
+	if len(ids) != 0 && ids[len(ids)-1] == id {
+		return
+	}
+	index.byName[key] = append(ids, id)
+}
+
+func (index *Index) loadAlternateNames(fileName string, wanted map[string]bool) error {
+	return scanLines(fileName, alternateNameColumns, func(fields []string) error {
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return err
+		}
+		if _, ok := index.places[id]; !ok {
+			return nil
+		}
+		if fields[2] == "wkdt" {
+			index.wikidata[id] = fields[3]
+			return nil
+		}
+		// besides ISO languages the column holds pseudo codes such as
+		// "link", "post" or "iata", which are never wanted
+		locale := locales.Canonical(fields[2])
+		if !wanted[locale] || fields[3] == "" {
+			return nil
+		}
+
+		if index.names[id] == nil {
+			index.names[id] = map[string][]AlternateName{}
+		}
+		index.names[id][locale] = append(index.names[id][locale], AlternateName{
+			Name:       fields[3],
+			Preferred:  fields[4] == "1",
+			Short:      fields[5] == "1",
+			Colloquial: fields[6] == "1",
+			Historic:   fields[7] == "1",
+		})
+		index.addName(fields[3], id)
+		return nil
+	})
+}
+
+// Find returns the places of the given kind known under name, countries
+// when country is set and populated places otherwise.
+func (index *Index) Find(name string, country bool) []*Place {
+	var found []*Place
+	seen := map[int]bool{}
+	for _, id := range index.byName[models.NormalizeName(name)] {
+		if p := index.places[id]; !seen[id] && p.Country == country {
+			found = append(found, p)
+			seen[id] = true
+		}
+	}
+	return found
+}
+
+// Wikidata returns the Q-ID of the Wikidata item of a place, or "".
+func (index *Index) Wikidata(id int) string {
+	return index.wikidata[id]
+}
+
+// Names returns the alternate names of a place per locale.
+func (index *Index) Names(id int) map[string][]AlternateName {
+	return index.names[id]
+}
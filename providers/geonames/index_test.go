@@ -0,0 +1,58 @@
+package geonames
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name string, lines ...string) string {
+	t.Helper()
+	fileName := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(fileName, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geonames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	places := writeFile(t, dir, "places.txt",
+		"1526384\tAlmaty\tAlmaty\tAlma-Ata,Verniy\t43.25\t76.91\tP\tPPLA\tKZ\t\t02\t\t\t\t2000900\t\t\t\t",
+		"1\tTiny\tTiny\t\t43.0\t76.0\tP\tPPL\tKZ\t\t02\t\t\t\t10\t\t\t\t",
+	)
+	alternateNames := writeFile(t, dir, "alternateNames.txt",
+		"10\t1526384\tru\tАлматы\t1\t\t\t",
+		"11\t1526384\tde\tAlmaty\t\t\t\t",
+		"12\t1526384\tja\tアルマトイ\t\t\t\t",
+		"13\t1526384\twkdt\tQ35493\t\t\t\t",
+		"14\t1526384\tlink\thttps://en.wikipedia.org/wiki/Almaty\t\t\t\t",
+		"15\t1\tru\tТини\t\t\t\t",
+	)
+
+	index, err := Load(places, alternateNames, []string{"ru", "en"}, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found := index.Find("alma-ata", false); len(found) != 1 || found[0].ID != 1526384 {
+		t.Errorf("Find(alma-ata) = %v, want Almaty from the places file", found)
+	}
+	if found := index.Find("Тини", false); len(found) != 0 {
+		t.Errorf("Find(Тини) = %v, want nothing below the minimum population", found)
+	}
+	names := index.Names(1526384)
+	if len(names) != 1 || len(names["ru"]) != 1 || names["ru"][0].Name != "Алматы" {
+		t.Errorf("Names() = %v, want only the ru name", names)
+	}
+	if got := index.Wikidata(1526384); got != "Q35493" {
+		t.Errorf("Wikidata() = %q, want Q35493", got)
+	}
+}
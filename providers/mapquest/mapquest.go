@@ -1,18 +1,19 @@
 package mapquest
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/lensgolda/geocapture/logfile"
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/interfaces"
 	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/providers/nominatim"
+	"github.com/lensgolda/geocapture/ratelimit"
 	"github.com/lensgolda/geocapture/settings"
 )
 
@@ -21,54 +22,85 @@ const (
 	providerName       = "mapquest"
 )
 
-var (
-	allowedLocales = map[string]string{
-		"name:ru": "ru",
-		"name:en": "en",
-		"name:kk": "kk",
-		"name:uk": "uk",
-	}
-	data []map[string]interface{}
-	city models.City
-)
-
 type Provider struct {
 	Name           string
 	FailedFileName string
-	RequestTimeout time.Duration
-	Client         *http.Client
+	Client         *httpclient.Client
+	// AllNames keeps the alternative names of every search result instead
+	// of only the localized names of the first one.
+	AllNames bool
+	// Reverse resolves cities with coordinates by reverse geocoding first.
+	Reverse bool
+}
+
+var _ interfaces.Provider = (*Provider)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name:     providerName,
+		Settings: []string{"MAPQUEST_API_KEY"},
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
 }
 
-func NewProvider() *Provider {
+func NewProvider() (*Provider, error) {
+	client, err := httpclient.New(
+		40*time.Second,
+		ratelimit.New(settings.Config.Mapquest.Rate, settings.Config.Mapquest.Burst),
+		settings.Config.Mapquest.Hosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Provider{
 		Name:           providerName,
 		FailedFileName: providerFailedFile,
-		RequestTimeout: time.Millisecond * 350,
-		Client: &http.Client{
-			Timeout: 40 * time.Second,
-		},
-	}
+		Client:         client,
+		AllNames:       settings.Config.App.AllNames,
+		Reverse:        settings.Config.App.Reverse,
+	}, nil
 }
 
-func (mapq *Provider) CreateCitiesRequest(city models.City) (*http.Request, error) {
-	req, err := http.NewRequest("GET", settings.Config.Mapquest.URL, nil)
+func (mapq *Provider) CreateRequest(ctx context.Context, model models.Model) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", settings.Config.Mapquest.URL, nil)
 	if err != nil {
 		return nil, err
 	}
 	q := req.URL.Query()
 	q.Add("key", settings.Config.Mapquest.ApiKey)
 	q.Add("format", "json")
-	if city.Name != nil {
-		q.Add(city.Type(), *city.Name)
-	} else {
-		if city.NameNational != nil {
-			q.Add(city.Type(), *city.NameNational)
+	switch m := model.(type) {
+	case models.City:
+		if m.Name != nil {
+			q.Add(m.Type(), *m.Name)
+		} else {
+			if m.NameNational != nil {
+				q.Add(m.Type(), *m.NameNational)
+			} else {
+				return nil, fmt.Errorf("%w: both names from cities table are NULL", models.ErrNoName)
+			}
+		}
+		// MapQuest serves the Nominatim search API
+		nominatim.AddCityContext(q, m)
+	case models.Country:
+		if m.Name != nil {
+			q.Add(m.Type(), *m.Name)
 		} else {
-			return nil, errors.New("both names from cities table are NULL")
+			if m.NameEN != nil {
+				q.Add(m.Type(), *m.NameEN)
+			} else {
+				return nil, fmt.Errorf("%w: both names from countries table are NULL", models.ErrNoName)
+			}
 		}
+	default:
+		return nil, models.ErrWrongModel
 	}
 	q.Add("addressdetails", "1")
 	q.Add("namedetails", "1")
+	q.Add("extratags", "1")
 	req.URL.RawQuery = q.Encode()
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("User-Agent", "MSIE/15.0")
@@ -76,114 +108,121 @@ func (mapq *Provider) CreateCitiesRequest(city models.City) (*http.Request, erro
 	return req, nil
 }
 
-func (mapq *Provider) ParseResponse(resp *http.Response) (map[string]interface{}, error) {
-	bytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+func (mapq *Provider) ParseResponse(bytes []byte) (models.NomResult, error) {
+	var data models.NomResult
 	if err := json.Unmarshal(bytes, &data); err != nil {
 		return nil, err
 	}
 
 	if len(data) == 0 {
-		return nil, errors.New("response data have zero length")
+		return nil, models.ErrEmptyResult
 	}
 
-	if namedetails, ok := data[0]["namedetails"]; ok {
-		if record, ok := namedetails.(map[string]interface{}); ok {
-			return record, nil
-		}
+	if data[0].Namedetail == nil {
+		return nil, models.ErrUnexpectedResponse
 	}
-	return nil, errors.New("nested data error, see data nested types and values")
+	return data, nil
 }
 
-func (mapq *Provider) ProcessData(db *sql.DB, data interface{}, city models.City) error {
-	if data, ok := data.(map[string]interface{}); ok {
-		for k, v := range data {
-			if locale, ok := allowedLocales[k]; ok {
-				stmt, err := db.Prepare("INSERT INTO cities_translations(city_id, locale, name, int_name) VALUES ($1, $2, $3, $4)")
-				if err != nil {
-					return err
-				}
-
-				_, err = stmt.Exec(city.ID, locale, v, data["int_name"])
-				if err != nil {
-					return err
-				}
-				log.Printf("Insert OK: CityID = %d, locale = %s, name = %s, int_name = %v\n", city.ID, locale, v, data["int_name"])
-			}
+// ProcessData collects every "name:<locale>" tag from the namedetails of the
+// result matching model best, and with AllNames the alternative names of all
+// matching results.
+func (mapq *Provider) ProcessData(model models.Model, data models.NomResult) (*models.Result, error) {
+	candidates := make([]models.Candidate, 0, len(data))
+	for _, location := range data {
+		candidates = append(candidates, location.Candidate())
+	}
+	ranked, err := models.Rank(model, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	best := data[ranked[0]]
+	result := models.NewResult(mapq.Name)
+	result.Names = best.Namedetail.Localized()
+	result.IntName = best.Namedetail.IntName()
+	result.Geo = best.Geo(mapq.Name)
+
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	if mapq.AllNames {
+		for _, i := range ranked {
+			data[i].Namedetail.AddAlternatives(result)
 		}
-		return nil
 	}
-	return errors.New("data is not of type map[string]interface{}")
+	return result, nil
+}
+
+func (mapq *Provider) ProviderName() string {
+	return mapq.Name
 }
 
 func (mapq *Provider) FailedFile() string {
 	return mapq.FailedFileName
 }
 
-func (mapq *Provider) ProcessCities(db *sql.DB) {
-	f, err := os.OpenFile("mapquest.failed", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// CreateReverseRequest asks for the city level place around a point.
+func (mapq *Provider) CreateReverseRequest(ctx context.Context, lat, lon float64) (*http.Request, error) {
+	params := nominatim.ReverseParams(lat, lon)
+	params.Add("key", settings.Config.Mapquest.ApiKey)
+	req, err := http.NewRequestWithContext(ctx, "GET", settings.Config.Mapquest.ReverseURL+params.Encode(), nil)
 	if err != nil {
-		log.Fatal("Failed to open file: ", err)
+		return nil, err
 	}
-	defer func() {
-		_ = f.Close()
-	}()
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("User-Agent", "MSIE/15.0")
 
-	rowsAll, err := db.Query("SELECT id, name, name_national FROM cities ORDER BY id")
+	return req, nil
+}
+
+// ReverseLookup resolves the place at the city's coordinates.
+func (mapq *Provider) ReverseLookup(ctx context.Context, city models.City, lat, lon float64) (*models.Result, error) {
+	req, err := mapq.CreateReverseRequest(ctx, lat, lon)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer func() {
-		_ = rowsAll.Close()
-	}()
 
-	var (
-		counter uint = 0
-		resp    *http.Response
-	)
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	for rowsAll.Next() {
-		counter += 1
-		if err := rowsAll.Scan(&city.ID, &city.Name, &city.NameNational); err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err)
-			continue
-		}
-		fmt.Printf("%d >>> CityID: %d Name: %v\n", counter, city.ID, city.Name)
+	body, err := mapq.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
 
-		req, err := mapq.CreateCitiesRequest(city)
-		if err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
+	location, err := nominatim.ParseReverseResponse(body)
+	if err != nil {
+		return nil, err
+	}
 
-		resp, err := mapq.Client.Do(req)
-		if err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
+	return mapq.ProcessData(city, models.NomResult{location})
+}
 
-		data, err := mapq.ParseResponse(resp)
-		if err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
+// Lookup resolves a city with coordinates from its position when Reverse is
+// set and searches by name otherwise, or when the position didn't help.
+func (mapq *Provider) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	if city, ok := model.(models.City); ok && mapq.Reverse {
+		if lat, lon, ok := city.Coordinates(); ok {
+			result, err := mapq.ReverseLookup(ctx, city, lat, lon)
+			if err == nil || !nominatim.SearchAfterReverse(err) {
+				return result, err
+			}
+			log.Printf("Reverse lookup of city %d failed, searching by name. Error: %s\n", city.ID, err.Error())
 		}
+	}
 
-		if err := mapq.ProcessData(db, data, city); err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
+	req, err := mapq.CreateRequest(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := mapq.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
 
-		time.Sleep(mapq.RequestTimeout)
+	data, err := mapq.ParseResponse(body)
+	if err != nil {
+		return nil, err
 	}
+
+	return mapq.ProcessData(model, data)
 }
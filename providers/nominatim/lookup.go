@@ -0,0 +1,140 @@
+package nominatim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+const (
+	LookupURL  = "https://nominatim.openstreetmap.org/lookup?"
+	DetailsURL = "https://nominatim.openstreetmap.org/details?"
+	// batchSize is the maximum number of osm_ids /lookup accepts.
+	batchSize = 50
+)
+
+func (nom *Nominatim) BatchSize() int {
+	return batchSize
+}
+
+// LookupBatch resolves the linked records of batch with a single /lookup
+// request. Records which aren't linked to an OSM object are searched by name
+// one by one.
+func (nom *Nominatim) LookupBatch(ctx context.Context, batch []models.Model) ([]*models.Result, []error) {
+	results := make([]*models.Result, len(batch))
+	errs := make([]error, len(batch))
+
+	var ids []string
+	linked := map[models.OSMRef][]int{}
+	for i, model := range batch {
+		ref := models.Linked(model)
+		if ref == nil {
+			results[i], errs[i] = nom.Lookup(ctx, model)
+			continue
+		}
+		if _, ok := linked[*ref]; !ok {
+			ids = append(ids, ref.String())
+		}
+		linked[*ref] = append(linked[*ref], i)
+	}
+	if len(ids) == 0 {
+		return results, errs
+	}
+
+	fail := func(err error) ([]*models.Result, []error) {
+		for _, indexes := range linked {
+			for _, i := range indexes {
+				errs[i] = err
+			}
+		}
+		return results, errs
+	}
+
+	req, err := lookupRequest(ctx, ids)
+	if err != nil {
+		return fail(err)
+	}
+	body, err := nom.Client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	var locations models.NomResult
+	if err := json.Unmarshal(body, &locations); err != nil {
+		return fail(err)
+	}
+
+	for _, location := range locations {
+		id, err := location.OSMID.Int64()
+		if err != nil {
+			continue
+		}
+		ref := models.OSMRef{Type: models.OSMType(location.OSMType), ID: id}
+		for _, i := range linked[ref] {
+			results[i], errs[i] = locationResult(location, nom.AllNames)
+		}
+		delete(linked, ref)
+	}
+	// objects missing from the response were deleted or merged in OSM
+	return fail(models.ErrEmptyResult)
+}
+
+func lookupRequest(ctx context.Context, ids []string) (*http.Request, error) {
+	params := url.Values{}
+	params.Add("format", "json")
+	params.Add("addressdetails", "1")
+	params.Add("namedetails", "1")
+	params.Add("extratags", "1")
+	params.Add("osm_ids", strings.Join(ids, ","))
+
+	return http.NewRequestWithContext(ctx, "GET", LookupURL+params.Encode(), nil)
+}
+
+// details fetches the names of a single OSM object from /details. A 400 or
+// 404 means the object was deleted or merged in OSM, which is an empty result
+// of the record rather than a failure of the service.
+func (nom *Nominatim) details(ctx context.Context, ref models.OSMRef) (*models.Result, error) {
+	params := url.Values{}
+	params.Add("format", "json")
+	params.Add("osmtype", ref.Letter())
+	params.Add("osmid", strconv.FormatInt(ref.ID, 10))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", DetailsURL+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := nom.Client.Do(req)
+	var httpErr *models.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusBadRequest || httpErr.StatusCode == http.StatusNotFound) {
+		return nil, fmt.Errorf("%w: %s %s", models.ErrEmptyResult, ref, httpErr.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var details models.NomDetails
+	if err := json.Unmarshal(body, &details); err != nil {
+		return nil, err
+	}
+	if details.Names == nil {
+		return nil, models.ErrUnexpectedResponse
+	}
+
+	result := models.NewResult(providerName)
+	result.Names = details.Names.Localized()
+	result.IntName = details.Names.IntName()
+	result.Geo = details.Geo(providerName)
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	if nom.AllNames {
+		details.Names.AddAlternatives(result)
+	}
+	return result, nil
+}
@@ -1,55 +1,76 @@
 package nominatim
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 	"time"
 
-	"github.com/lensgolda/geocapture/logfile"
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/interfaces"
 	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/ratelimit"
+	"github.com/lensgolda/geocapture/settings"
 )
 
 const (
 	URL                = "https://nominatim.openstreetmap.org/search?"
 	providerName       = "nominatim"
 	providerFailedFile = "nominatim.failed"
-	localeRU           = "ru"
-	localeEN           = "en"
-	localeKK           = "kk"
-	localeUK           = "uk"
-)
-
-var (
-	city    models.City
-	country models.Country
 )
 
 type Nominatim struct {
 	Name           string
 	FailedFileName string
-	RequestTimeout time.Duration
+	Client         *httpclient.Client
+	// AllNames keeps the alternative names of every search result instead
+	// of only the localized names of the first one.
+	AllNames bool
+	// Reverse resolves cities with coordinates by reverse geocoding first.
+	Reverse bool
+}
+
+var _ interfaces.BatchProvider = (*Nominatim)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name: providerName,
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
 }
 
-func NewProvider() *Nominatim {
+func NewProvider() (*Nominatim, error) {
+	client, err := httpclient.New(
+		30*time.Second,
+		ratelimit.New(settings.Config.Nominatim.Rate, settings.Config.Nominatim.Burst),
+		settings.Config.Nominatim.Hosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Nominatim{
 		Name:           providerName,
 		FailedFileName: providerFailedFile,
-		RequestTimeout: time.Millisecond * 1500,
-	}
+		Client:         client,
+		AllNames:       settings.Config.App.AllNames,
+		Reverse:        settings.Config.App.Reverse,
+	}, nil
 }
 
-func sendSearchRequest(model models.Model) (*http.Response, error) {
+func searchRequest(ctx context.Context, model models.Model) (*http.Request, error) {
 	params := url.Values{}
 	params.Add("format", "json")
-	//params.Add("addressdetails", "1")
+	params.Add("addressdetails", "1")
 	params.Add("namedetails", "1")
+	params.Add("extratags", "1")
 	city, ok1 := model.(models.City)
 	country, ok2 := model.(models.Country)
 
@@ -61,9 +82,10 @@ func sendSearchRequest(model models.Model) (*http.Response, error) {
 			if city.NameNational != nil {
 				params.Add(city.Type(), *city.NameNational)
 			} else {
-				return nil, errors.New("both names from cities table are NULL")
+				return nil, fmt.Errorf("%w: both names from cities table are NULL", models.ErrNoName)
 			}
 		}
+		AddCityContext(params, city)
 	case ok2:
 		if country.Name != nil {
 			params.Add(country.Type(), *country.Name)
@@ -71,208 +93,119 @@ func sendSearchRequest(model models.Model) (*http.Response, error) {
 			if country.NameEN != nil {
 				params.Add(country.Type(), *country.NameEN)
 			} else {
-				return nil, errors.New("both names from countries table are NULL")
+				return nil, fmt.Errorf("%w: both names from countries table are NULL", models.ErrNoName)
 			}
 		}
 	default:
-		return nil, errors.New("wrong model type")
+		return nil, models.ErrWrongModel
 	}
 
-	reqURL := URL + params.Encode()
-	resp, err := http.Get(reqURL)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+	return http.NewRequestWithContext(ctx, "GET", URL+params.Encode(), nil)
 }
 
-func parseSearchResponse(resp *http.Response) (models.AltName, error) {
-	bytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return models.AltName{}, err
+// AddCityContext restricts a city search to the city's country and region.
+// The country name is only sent when its code is unknown.
+func AddCityContext(params url.Values, city models.City) {
+	switch {
+	case city.CountryCode != nil && *city.CountryCode != "":
+		params.Add("countrycodes", strings.ToLower(*city.CountryCode))
+	case city.CountryName != nil && *city.CountryName != "":
+		params.Add("country", *city.CountryName)
 	}
+	if city.Region != nil && *city.Region != "" {
+		params.Add("state", *city.Region)
+	}
+}
+
+func parseSearchResponse(bytes []byte) (models.NomResult, error) {
 	result := models.NomResult{}
 	if err := json.Unmarshal(bytes, &result); err != nil {
-		return models.AltName{}, err
+		return nil, err
 	}
 	if len(result) == 0 {
-		return models.AltName{}, errors.New("response data have zero length")
+		return nil, models.ErrEmptyResult
 	}
-
-	return result[0].Namedetail, nil
+	return result, nil
 }
 
-func insertLocale(db *sql.DB, locale string, altName models.AltName, model models.Model) error {
-	stmt, err := db.Prepare("INSERT INTO countries_translations_temp(country_id, locale, name, int_name) VALUES ($1, $2, $3, $4)")
+// processResponseData takes the names of the best match for model. With
+// allNames the alternative names of it and the names of the other matches
+// are kept too.
+func processResponseData(model models.Model, locations models.NomResult, allNames bool) (*models.Result, error) {
+	candidates := make([]models.Candidate, 0, len(locations))
+	for _, location := range locations {
+		candidates = append(candidates, location.Candidate())
+	}
+	ranked, err := models.Rank(model, candidates)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var localizedName string
-	switch locale {
-	case localeRU:
-		localizedName = *altName.NameRu
-	case localeEN:
-		localizedName = *altName.NameEn
-	case localeKK:
-		localizedName = *altName.NameKk
-	case localeUK:
-		localizedName = *altName.NameUk
-	}
-	_, err = stmt.Exec(model.Id(), locale, localizedName, altName.IntName)
+	result, err := locationResult(locations[ranked[0]], allNames)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if allNames {
+		for _, i := range ranked[1:] {
+			locations[i].Namedetail.AddAlternatives(result)
+		}
 	}
-	log.Println("INSERT: OK")
-	return nil
+	return result, nil
 }
 
-func processResponseData(db *sql.DB, altName models.AltName, model models.Model) error {
-	var err error
-	if altName.NameRu == nil &&
-		altName.NameEn == nil &&
-		altName.NameKk == nil &&
-		altName.NameUk == nil {
-		return errors.New("response data doesn't contain appropriate locale")
-	}
-	if altName.NameRu != nil {
-		fmt.Printf("Locale: %v, AltName: %v\n", localeRU, altName.NameRu)
-		err = insertLocale(db, localeRU, altName, model)
-	}
-	if altName.NameEn != nil {
-		fmt.Printf("Locale: %v, value: %v\n", localeEN, altName.NameEn)
-		err = insertLocale(db, localeEN, altName, model)
-	}
-	if altName.NameKk != nil {
-		fmt.Printf("Locale: %v, value: %v\n", localeKK, altName.NameKk)
-		err = insertLocale(db, localeKK, altName, model)
+// locationResult takes the names and position of a single location.
+func locationResult(location models.Location, allNames bool) (*models.Result, error) {
+	result := models.NewResult(providerName)
+	result.Names = location.Namedetail.Localized()
+	result.IntName = location.Namedetail.IntName()
+	result.Geo = location.Geo(providerName)
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
 	}
-	if altName.NameUk != nil {
-		fmt.Printf("Locale: %v, value: %v\n", localeUK, altName.NameUk)
-		err = insertLocale(db, localeUK, altName, model)
+	if allNames {
+		location.Namedetail.AddAlternatives(result)
 	}
-	return err
+	return result, nil
+}
+
+func (nom *Nominatim) ProviderName() string {
+	return nom.Name
 }
 
 func (nom *Nominatim) FailedFile() string {
 	return nom.FailedFileName
 }
 
-func (nom *Nominatim) CountryNameLocalize(db *sql.DB) {
-	f, err := os.OpenFile(nom.FailedFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal("Failed to open file: ", err)
-	}
-	defer func() {
-		_ = f.Close()
-	}()
-
-	rowsAll, err := db.Query("SELECT id, name, name_en FROM countries ORDER BY id")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer func() {
-		_ = rowsAll.Close()
-	}()
-
-	var (
-		counter uint = 0
-		resp    *http.Response
-	)
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	for rowsAll.Next() {
-		counter += 1
-
-		if counter >= 2 {
-			break
-		}
-
-		if err := rowsAll.Scan(&country.ID, &country.Name, &country.NameEN); err != nil {
-			logfile.LogFailed(f, country.ID)
-			log.Println(err.Error())
-			continue
-		}
-		fmt.Printf("%d >>> CountryID: %d Name: %s, NameEn: %s\n", counter, country.ID, *country.Name, *country.NameEN)
-
-		resp, err = sendSearchRequest(country)
-		if err != nil {
-			log.Println(err.Error())
-			continue
-		}
-
-		nd, err := parseSearchResponse(resp)
-		if err != nil {
-			log.Println(err.Error())
-			continue
-		}
-		if err := processResponseData(db, nd, country); err != nil {
-			log.Println(err.Error())
-			continue
+// Lookup fetches the details of the OSM object model was linked to before.
+// Otherwise, with Reverse, a city with coordinates is resolved from its
+// position, and everything else is searched by name.
+func (nom *Nominatim) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	if ref := models.Linked(model); ref != nil {
+		return nom.details(ctx, *ref)
+	}
+	if city, ok := model.(models.City); ok && nom.Reverse {
+		if lat, lon, ok := city.Coordinates(); ok {
+			result, err := nom.reverse(ctx, city, lat, lon)
+			if err == nil || !SearchAfterReverse(err) {
+				return result, err
+			}
+			log.Printf("Reverse lookup of city %d failed, searching by name. Error: %s\n", city.ID, err.Error())
 		}
-
-		time.Sleep(nom.RequestTimeout)
 	}
-}
 
-func (nom *Nominatim) CityNameLocalize(db *sql.DB) {
-	f, err := os.OpenFile(nom.FailedFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	req, err := searchRequest(ctx, model)
 	if err != nil {
-		log.Fatal("Failed to open file: ", err)
+		return nil, err
 	}
-	defer func() {
-		_ = f.Close()
-	}()
 
-	rowsAll, err := db.Query("SELECT id, name, name_national FROM cities ORDER BY id")
+	body, err := nom.Client.Do(req)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer func() {
-		_ = rowsAll.Close()
-	}()
-
-	var (
-		counter uint = 0
-		resp    *http.Response
-	)
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	for rowsAll.Next() {
-		counter += 1
-
-		// limit for testing
-		if counter >= 2 {
-			break
-		}
-
-		if err := rowsAll.Scan(&city.ID, &city.Name, &city.NameNational); err != nil {
-			logfile.LogFailed(f, city.ID)
-			log.Println(err.Error())
-			continue
-		}
-		fmt.Printf("%d >>> CityID: %d Name: %v\n", counter, city.ID, city.Name)
-
-		resp, err = sendSearchRequest(country)
-		if err != nil {
-			log.Println(err.Error())
-			continue
-		}
-
-		nd, err := parseSearchResponse(resp)
-		if err != nil {
-			log.Println(err.Error())
-			continue
-		}
-		if err := processResponseData(db, nd, country); err != nil {
-			log.Println(err.Error())
-			continue
-		}
 
-		time.Sleep(nom.RequestTimeout)
+	locations, err := parseSearchResponse(body)
+	if err != nil {
+		return nil, err
 	}
+	return processResponseData(model, locations, nom.AllNames)
 }
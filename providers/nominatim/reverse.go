@@ -0,0 +1,73 @@
+package nominatim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/lensgolda/geocapture/models"
+)
+
+const (
+	ReverseURL = "https://nominatim.openstreetmap.org/reverse?"
+	// reverseZoom asks /reverse for the city level place around a point.
+	reverseZoom = "10"
+)
+
+// ReverseParams returns the query of a city level /reverse request for the
+// given point.
+func ReverseParams(lat, lon float64) url.Values {
+	params := url.Values{}
+	params.Add("format", "json")
+	params.Add("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Add("lon", strconv.FormatFloat(lon, 'f', -1, 64))
+	params.Add("zoom", reverseZoom)
+	params.Add("addressdetails", "1")
+	params.Add("namedetails", "1")
+	params.Add("extratags", "1")
+	return params
+}
+
+// ParseReverseResponse decodes a /reverse response, turning a reported miss
+// into ErrEmptyResult.
+func ParseReverseResponse(bytes []byte) (models.Location, error) {
+	var reverse models.NomReverse
+	if err := json.Unmarshal(bytes, &reverse); err != nil {
+		return models.Location{}, err
+	}
+	if reverse.Error != "" {
+		return models.Location{}, models.ErrEmptyResult
+	}
+	if reverse.Namedetail == nil {
+		return models.Location{}, models.ErrUnexpectedResponse
+	}
+	return reverse.Location, nil
+}
+
+// SearchAfterReverse tells whether a failed reverse lookup should be
+// followed by a search by name: only when the point didn't lead to a usable
+// place.
+func SearchAfterReverse(err error) bool {
+	return errors.Is(err, models.ErrEmptyResult) || errors.Is(err, models.ErrNoMatch) ||
+		errors.Is(err, models.ErrNoLocale) || errors.Is(err, models.ErrUnexpectedResponse)
+}
+
+// reverse resolves the place at the city's coordinates.
+func (nom *Nominatim) reverse(ctx context.Context, city models.City, lat, lon float64) (*models.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", ReverseURL+ReverseParams(lat, lon).Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := nom.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	location, err := ParseReverseResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	return processResponseData(city, models.NomResult{location}, nom.AllNames)
+}
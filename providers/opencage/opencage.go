@@ -0,0 +1,280 @@
+package opencage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/ratelimit"
+	"github.com/lensgolda/geocapture/settings"
+)
+
+const (
+	providerName       = "opencage"
+	providerFailedFile = "opencage.failed"
+)
+
+// placeTypes are the values of components._type accepted for each model.
+var placeTypes = map[string][]string{
+	"city":    {"city", "town", "village", "hamlet"},
+	"country": {"country"},
+}
+
+// OpenCage geocodes through the OpenCage API. Names come in the requested
+// language only, so every record takes a request for the local names and
+// one per language.
+type OpenCage struct {
+	Name           string
+	FailedFileName string
+	Client         *httpclient.Client
+	Languages      []string
+	// AllNames keeps the names of the other matching results as
+	// alternatives.
+	AllNames bool
+}
+
+var _ interfaces.Provider = (*OpenCage)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name:     providerName,
+		Settings: []string{"OPENCAGE_API_KEY"},
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
+}
+
+func NewProvider() (*OpenCage, error) {
+	list := settings.Config.OpenCage.Languages
+	if len(list) == 0 {
+		list = settings.Config.App.Locales
+	}
+	languages, err := locales.Parse(list)
+	if err != nil {
+		return nil, fmt.Errorf("OPENCAGE_LANGUAGES: %w", err)
+	}
+	if len(languages) == 0 {
+		return nil, errors.New("OPENCAGE_LANGUAGES is empty")
+	}
+
+	client, err := httpclient.New(
+		30*time.Second,
+		ratelimit.New(settings.Config.OpenCage.Rate, settings.Config.OpenCage.Burst),
+		settings.Config.OpenCage.Hosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenCage{
+		Name:           providerName,
+		FailedFileName: providerFailedFile,
+		Client:         client,
+		Languages:      languages,
+		AllNames:       settings.Config.App.AllNames,
+	}, nil
+}
+
+func (oc *OpenCage) CreateRequest(ctx context.Context, model models.Model, lang string) (*http.Request, error) {
+	params := url.Values{}
+	params.Add("key", settings.Config.OpenCage.ApiKey)
+	params.Add("language", lang)
+	params.Add("limit", "10")
+	params.Add("no_record", "1")
+
+	switch m := model.(type) {
+	case models.City:
+		if m.Name != nil {
+			params.Add("q", *m.Name)
+		} else {
+			if m.NameNational != nil {
+				params.Add("q", *m.NameNational)
+			} else {
+				return nil, fmt.Errorf("%w: both names from cities table are NULL", models.ErrNoName)
+			}
+		}
+		if m.CountryCode != nil && *m.CountryCode != "" {
+			params.Add("countrycode", strings.ToLower(*m.CountryCode))
+		}
+	case models.Country:
+		if m.Name != nil {
+			params.Add("q", *m.Name)
+		} else {
+			if m.NameEN != nil {
+				params.Add("q", *m.NameEN)
+			} else {
+				return nil, fmt.Errorf("%w: both names from countries table are NULL", models.ErrNoName)
+			}
+		}
+	default:
+		return nil, models.ErrWrongModel
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", settings.Config.OpenCage.URL+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+
+	return req, nil
+}
+
+// ParseResponse returns the results which are places of the model's kind,
+// skipping streets, buildings and the like.
+func (oc *OpenCage) ParseResponse(bytes []byte, model models.Model) ([]models.OpenCageResult, error) {
+	var data models.OpenCageResponse
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+
+	var results []models.OpenCageResult
+	for _, result := range data.Results {
+		if accepted(model, result.Type()) && result.Name() != "" {
+			results = append(results, result)
+		}
+	}
+	if len(results) == 0 {
+		return nil, models.ErrEmptyResult
+	}
+	return results, nil
+}
+
+func accepted(model models.Model, placeType string) bool {
+	for _, t := range placeTypes[model.Type()] {
+		if t == placeType {
+			return true
+		}
+	}
+	return false
+}
+
+func (oc *OpenCage) search(ctx context.Context, model models.Model, lang string) ([]models.OpenCageResult, error) {
+	req, err := oc.CreateRequest(ctx, model, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := oc.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return oc.ParseResponse(body, model)
+}
+
+// nativeLang asks OpenCage for the names in the local language of each
+// place.
+const nativeLang = "native"
+
+// Lookup picks the best matching result from the search for local names
+// and takes its name in every language from the result for the same place.
+func (oc *OpenCage) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	local, err := oc.search(ctx, model, nativeLang)
+	if err != nil {
+		return nil, err
+	}
+	ranked, err := rank(model, local)
+	if err != nil {
+		return nil, err
+	}
+
+	best := local[ranked[0]]
+	result := models.NewResult(oc.Name)
+	result.Geo = best.Geo(oc.Name)
+
+	for _, lang := range oc.Languages {
+		results, err := oc.search(ctx, model, lang)
+		switch {
+		case errors.Is(err, models.ErrEmptyResult):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		ranked, err := rank(model, results)
+		if err != nil {
+			continue
+		}
+		oc.addNames(result, lang, best, local, results, ranked)
+	}
+
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	return result, nil
+}
+
+func rank(model models.Model, results []models.OpenCageResult) ([]int, error) {
+	candidates := make([]models.Candidate, 0, len(results))
+	for _, result := range results {
+		candidates = append(candidates, result.Candidate())
+	}
+	return models.Rank(model, candidates)
+}
+
+// addNames takes the name of the result for the same place as best for
+// locale, and with AllNames the names of the other results as alternatives.
+// OpenCage returns the native name of a place it has no translation for, so
+// a name equal to it only counts when locale is an official language of the
+// place's country.
+func (oc *OpenCage) addNames(result *models.Result, locale string, best models.OpenCageResult, local, results []models.OpenCageResult, ranked []int) {
+	var others []string
+	for _, i := range ranked {
+		name := results[i].Name()
+		if name == "" || (name == localName(local, results[i]) && locales.Foreign(locale, results[i].Component("country_code"))) {
+			continue
+		}
+		if samePlace(results[i], best) {
+			if _, ok := result.Names[locale]; !ok {
+				result.Names[locale] = name
+			}
+			continue
+		}
+		others = append(others, name)
+	}
+	if !oc.AllNames {
+		return
+	}
+	for _, name := range others {
+		result.AddAlternative(locale, models.Alternative{Name: name, Kind: models.KindName, Providers: []string{oc.Name}})
+	}
+}
+
+// localName returns the name of r's place among the results of the search
+// for local names.
+func localName(local []models.OpenCageResult, r models.OpenCageResult) string {
+	for _, l := range local {
+		if samePlace(l, r) {
+			return l.Name()
+		}
+	}
+	return ""
+}
+
+// samePlace compares results by their OSM object, or by position when
+// OpenCage didn't link them to OSM.
+func samePlace(a, b models.OpenCageResult) bool {
+	refA, refB := a.OSMRef(), b.OSMRef()
+	if refA != nil || refB != nil {
+		return refA != nil && refB != nil && *refA == *refB
+	}
+	return a.Geometry == b.Geometry
+}
+
+func (oc *OpenCage) ProviderName() string {
+	return oc.Name
+}
+
+func (oc *OpenCage) FailedFile() string {
+	return oc.FailedFileName
+}
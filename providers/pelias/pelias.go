@@ -0,0 +1,263 @@
+package pelias
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/ratelimit"
+	"github.com/lensgolda/geocapture/settings"
+)
+
+const (
+	providerName       = "pelias"
+	providerFailedFile = "pelias.failed"
+)
+
+// Pelias searches a Pelias instance for the local names of places and
+// fetches the matches from /v1/place in each of Languages, as Pelias returns
+// names in a single language per request.
+type Pelias struct {
+	Name           string
+	FailedFileName string
+	Client         *httpclient.Client
+	URL            string
+	Languages      []string
+	// AllNames keeps the names of the other matching search results as
+	// alternatives.
+	AllNames bool
+}
+
+var _ interfaces.Provider = (*Pelias)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name:     providerName,
+		Settings: []string{"PELIAS_URL"},
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
+}
+
+func NewProvider() (*Pelias, error) {
+	list := settings.Config.Pelias.Languages
+	if len(list) == 0 {
+		list = settings.Config.App.Locales
+	}
+	languages, err := locales.Parse(list)
+	if err != nil {
+		return nil, fmt.Errorf("PELIAS_LANGUAGES: %w", err)
+	}
+	if len(languages) == 0 {
+		return nil, errors.New("PELIAS_LANGUAGES is empty")
+	}
+
+	client, err := httpclient.New(
+		30*time.Second,
+		ratelimit.New(settings.Config.Pelias.Rate, settings.Config.Pelias.Burst),
+		settings.Config.Pelias.Hosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pelias{
+		Name:           providerName,
+		FailedFileName: providerFailedFile,
+		Client:         client,
+		URL:            strings.TrimRight(settings.Config.Pelias.URL, "/"),
+		Languages:      languages,
+		AllNames:       settings.Config.App.AllNames,
+	}, nil
+}
+
+func (pel *Pelias) newRequest(ctx context.Context, endpoint string, params url.Values) (*http.Request, error) {
+	if settings.Config.Pelias.ApiKey != "" {
+		params.Add("api_key", settings.Config.Pelias.ApiKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", pel.URL+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+
+	return req, nil
+}
+
+// CreateSearchRequest searches the locality or country layer, restricted to
+// the city's country when it is known.
+func (pel *Pelias) CreateSearchRequest(ctx context.Context, model models.Model, lang string) (*http.Request, error) {
+	params := url.Values{}
+	params.Add("lang", lang)
+	params.Add("size", "10")
+
+	switch m := model.(type) {
+	case models.City:
+		if m.Name != nil {
+			params.Add("text", *m.Name)
+		} else {
+			if m.NameNational != nil {
+				params.Add("text", *m.NameNational)
+			} else {
+				return nil, fmt.Errorf("%w: both names from cities table are NULL", models.ErrNoName)
+			}
+		}
+		params.Add("layers", "locality")
+		if m.CountryCode != nil && *m.CountryCode != "" {
+			params.Add("boundary.country", strings.ToUpper(*m.CountryCode))
+		}
+	case models.Country:
+		if m.Name != nil {
+			params.Add("text", *m.Name)
+		} else {
+			if m.NameEN != nil {
+				params.Add("text", *m.NameEN)
+			} else {
+				return nil, fmt.Errorf("%w: both names from countries table are NULL", models.ErrNoName)
+			}
+		}
+		params.Add("layers", "country")
+	default:
+		return nil, models.ErrWrongModel
+	}
+
+	return pel.newRequest(ctx, "/v1/search", params)
+}
+
+// CreatePlaceRequest fetches places by their gids.
+func (pel *Pelias) CreatePlaceRequest(ctx context.Context, gids []string, lang string) (*http.Request, error) {
+	params := url.Values{}
+	params.Add("ids", strings.Join(gids, ","))
+	params.Add("lang", lang)
+
+	return pel.newRequest(ctx, "/v1/place", params)
+}
+
+func (pel *Pelias) ParseResponse(bytes []byte) (*models.PeliasResult, error) {
+	var data models.PeliasResult
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Features) == 0 {
+		return nil, models.ErrEmptyResult
+	}
+	return &data, nil
+}
+
+func (pel *Pelias) fetch(req *http.Request) (*models.PeliasResult, error) {
+	body, err := pel.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return pel.ParseResponse(body)
+}
+
+func (pel *Pelias) search(ctx context.Context, model models.Model, lang string) (*models.PeliasResult, error) {
+	req, err := pel.CreateSearchRequest(ctx, model, lang)
+	if err != nil {
+		return nil, err
+	}
+	return pel.fetch(req)
+}
+
+func (pel *Pelias) place(ctx context.Context, gids []string, lang string) ([]models.PeliasFeature, error) {
+	req, err := pel.CreatePlaceRequest(ctx, gids, lang)
+	if err != nil {
+		return nil, err
+	}
+	data, err := pel.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	return data.Features, nil
+}
+
+// localLang is a language no place has a name in ("no linguistic
+// content"). Pelias labels a place with its default name when it has none
+// in the requested language, and reports the language it used in
+// geocoding.query.lang, so the names of a search in localLang are the local
+// ones unless Pelias defaulted to another language.
+const localLang = "zxx"
+
+// Lookup picks the best matching search result and takes its names, and
+// with AllNames those of the other matches as alternatives, from /v1/place.
+// A name equal to the local one is Pelias' fallback for a missing
+// translation when lang isn't an official language of the place's country,
+// and is skipped then.
+func (pel *Pelias) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	data, err := pel.search(ctx, model, localLang)
+	if err != nil {
+		return nil, err
+	}
+	features := data.Features
+	candidates := make([]models.Candidate, 0, len(features))
+	for _, feature := range features {
+		candidates = append(candidates, feature.Candidate())
+	}
+	ranked, err := models.Rank(model, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	best := features[ranked[0]]
+	result := models.NewResult(pel.Name)
+	result.Geo = best.Geo(pel.Name)
+
+	gids := []string{best.Properties.GID}
+	if pel.AllNames {
+		for _, i := range ranked[1:] {
+			gids = append(gids, features[i].Properties.GID)
+		}
+	}
+	local := map[string]string{}
+	if lang := data.Geocoding.Query.Lang; lang.ISO6393 == localLang && !lang.Defaulted {
+		for _, feature := range features {
+			local[feature.Properties.GID] = feature.Properties.Name
+		}
+	}
+
+	for _, lang := range pel.Languages {
+		places, err := pel.place(ctx, gids, lang)
+		switch {
+		case errors.Is(err, models.ErrEmptyResult):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		for _, place := range places {
+			name := place.Properties.Name
+			if name == "" || (name == local[place.Properties.GID] && locales.Foreign(lang, place.Properties.CountryCode)) {
+				continue
+			}
+			if place.Properties.GID == best.Properties.GID {
+				result.Names[lang] = name
+			} else {
+				result.AddAlternative(lang, models.Alternative{Name: name, Kind: models.KindName, Providers: []string{pel.Name}})
+			}
+		}
+	}
+
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	return result, nil
+}
+
+func (pel *Pelias) ProviderName() string {
+	return pel.Name
+}
+
+func (pel *Pelias) FailedFile() string {
+	return pel.FailedFileName
+}
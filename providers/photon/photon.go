@@ -0,0 +1,249 @@
+package photon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/ratelimit"
+	"github.com/lensgolda/geocapture/settings"
+)
+
+const (
+	providerName       = "photon"
+	providerFailedFile = "photon.failed"
+)
+
+var (
+	cityTags    = []string{"place:city", "place:town", "place:village", "place:hamlet"}
+	countryTags = []string{"place:country"}
+)
+
+// Photon looks places up in a Photon instance. Photon returns the name in a
+// single language per request, falling back to the local name when there is
+// no translation, so every record takes a request for the local name and
+// one for each of Languages.
+type Photon struct {
+	Name           string
+	FailedFileName string
+	Client         *httpclient.Client
+	Languages      []string
+	// AllNames keeps the names of the other matching features as
+	// alternatives.
+	AllNames bool
+}
+
+var _ interfaces.Provider = (*Photon)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name: providerName,
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
+}
+
+func NewProvider() (*Photon, error) {
+	list := settings.Config.Photon.Languages
+	if len(list) == 0 {
+		list = settings.Config.App.Locales
+	}
+	languages, err := locales.Parse(list)
+	if err != nil {
+		return nil, fmt.Errorf("PHOTON_LANGUAGES: %w", err)
+	}
+	if len(languages) == 0 {
+		return nil, errors.New("PHOTON_LANGUAGES is empty")
+	}
+
+	client, err := httpclient.New(
+		30*time.Second,
+		ratelimit.New(settings.Config.Photon.Rate, settings.Config.Photon.Burst),
+		settings.Config.Photon.Hosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Photon{
+		Name:           providerName,
+		FailedFileName: providerFailedFile,
+		Client:         client,
+		Languages:      languages,
+		AllNames:       settings.Config.App.AllNames,
+	}, nil
+}
+
+func (ph *Photon) CreateRequest(ctx context.Context, model models.Model, lang string) (*http.Request, error) {
+	params := url.Values{}
+	params.Add("lang", lang)
+	params.Add("limit", "10")
+
+	var tags []string
+	switch m := model.(type) {
+	case models.City:
+		if m.Name != nil {
+			params.Add("q", *m.Name)
+		} else {
+			if m.NameNational != nil {
+				params.Add("q", *m.NameNational)
+			} else {
+				return nil, fmt.Errorf("%w: both names from cities table are NULL", models.ErrNoName)
+			}
+		}
+		tags = cityTags
+	case models.Country:
+		if m.Name != nil {
+			params.Add("q", *m.Name)
+		} else {
+			if m.NameEN != nil {
+				params.Add("q", *m.NameEN)
+			} else {
+				return nil, fmt.Errorf("%w: both names from countries table are NULL", models.ErrNoName)
+			}
+		}
+		tags = countryTags
+	default:
+		return nil, models.ErrWrongModel
+	}
+	for _, tag := range tags {
+		params.Add("osm_tag", tag)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", settings.Config.Photon.URL+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+
+	return req, nil
+}
+
+func (ph *Photon) ParseResponse(bytes []byte) ([]models.PhotonFeature, error) {
+	var data models.PhotonResult
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Features) == 0 {
+		return nil, models.ErrEmptyResult
+	}
+	return data.Features, nil
+}
+
+func (ph *Photon) search(ctx context.Context, model models.Model, lang string) ([]models.PhotonFeature, error) {
+	req, err := ph.CreateRequest(ctx, model, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ph.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ph.ParseResponse(body)
+}
+
+// defaultLang asks Photon for the local names of the features.
+const defaultLang = "default"
+
+// Lookup picks the best matching feature from the search for local names
+// and takes its name in every language from the feature with the same OSM
+// object.
+func (ph *Photon) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	features, err := ph.search(ctx, model, defaultLang)
+	if err != nil {
+		return nil, err
+	}
+	ranked, err := rank(model, features)
+	if err != nil {
+		return nil, err
+	}
+
+	best := features[ranked[0]]
+	ref := best.Ref()
+	result := models.NewResult(ph.Name)
+	result.Geo = best.Geo(ph.Name)
+	local := map[models.OSMRef]string{}
+	for _, feature := range features {
+		local[feature.Ref()] = feature.Properties.Name
+	}
+
+	for _, lang := range ph.Languages {
+		features, err := ph.search(ctx, model, lang)
+		switch {
+		case errors.Is(err, models.ErrEmptyResult):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		ranked, err := rank(model, features)
+		if err != nil {
+			continue
+		}
+		ph.addNames(result, lang, ref, local, features, ranked)
+	}
+
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	return result, nil
+}
+
+func rank(model models.Model, features []models.PhotonFeature) ([]int, error) {
+	candidates := make([]models.Candidate, 0, len(features))
+	for _, feature := range features {
+		candidates = append(candidates, feature.Candidate())
+	}
+	return models.Rank(model, candidates)
+}
+
+// addNames takes the name of the feature ref points to for locale, and with
+// AllNames the names of the other matching features as alternatives. Photon
+// falls back to the local name for a missing translation, so a name equal to
+// the local one is skipped when locale isn't an official language of the
+// feature's country. Otherwise it is the name in that language, e.g. Алматы
+// in ru.
+func (ph *Photon) addNames(result *models.Result, locale string, ref models.OSMRef, local map[models.OSMRef]string, features []models.PhotonFeature, ranked []int) {
+	translated := func(feature models.PhotonFeature) bool {
+		name := feature.Properties.Name
+		if name == local[feature.Ref()] && locales.Foreign(locale, feature.Properties.CountryCode) {
+			return false
+		}
+		return name != ""
+	}
+	for _, i := range ranked {
+		if features[i].Ref() == ref {
+			if translated(features[i]) {
+				result.Names[locale] = features[i].Properties.Name
+			}
+			break
+		}
+	}
+	if !ph.AllNames {
+		return
+	}
+	for _, i := range ranked {
+		if features[i].Ref() != ref && translated(features[i]) {
+			result.AddAlternative(locale, models.Alternative{Name: features[i].Properties.Name, Kind: models.KindName, Providers: []string{ph.Name}})
+		}
+	}
+}
+
+func (ph *Photon) ProviderName() string {
+	return ph.Name
+}
+
+func (ph *Photon) FailedFile() string {
+	return ph.FailedFileName
+}
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lensgolda/geocapture/interfaces"
+)
+
+// Registration describes a provider package: its name, the environment
+// variables it can't work without and a constructor.
+type Registration struct {
+	Name     string
+	Settings []string
+	New      func() (interfaces.Provider, error)
+}
+
+var registry = map[string]Registration{}
+
+// Register makes a provider available by name. It is meant to be called
+// from the provider package's init function and panics on duplicates.
+func Register(r Registration) {
+	if _, ok := registry[r.Name]; ok {
+		panic(fmt.Sprintf("provider %q registered twice", r.Name))
+	}
+	registry[r.Name] = r
+}
+
+func Get(name string) (Registration, bool) {
+	r, ok := registry[name]
+	return r, ok
+}
+
+// All returns every registered provider sorted by name.
+func All() []Registration {
+	all := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		all = append(all, r)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+func Names() []string {
+	var names []string
+	for _, r := range All() {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// Missing lists required settings that are not set in the environment.
+func (r Registration) Missing() []string {
+	var missing []string
+	for _, key := range r.Settings {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// New builds a registered provider after checking its required settings.
+func New(name string) (interfaces.Provider, error) {
+	r, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, available: %s", name, strings.Join(Names(), ", "))
+	}
+	if missing := r.Missing(); len(missing) != 0 {
+		return nil, fmt.Errorf("provider %q requires %s", name, strings.Join(missing, ", "))
+	}
+	return r.New()
+}
@@ -0,0 +1,123 @@
+package wikidata
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Dump holds the labels and aliases of the items in a Wikidata JSON dump.
+type Dump struct {
+	entities map[string]*Entity
+}
+
+var _ Source = (*Dump)(nil)
+
+type dumpEntity struct {
+	ID     string `json:"id"`
+	Labels map[string]struct {
+		Value string `json:"value"`
+	} `json:"labels"`
+	Aliases map[string][]struct {
+		Value string `json:"value"`
+	} `json:"aliases"`
+}
+
+// LoadDump reads a Wikidata JSON dump, plain, .gz or .bz2, keeping the
+// labels and aliases in the wanted languages. The dump is a JSON array with
+// one item per line; a file with one item per line and no array works too.
+// Full dumps don't fit in memory, filter them down to places first.
+func LoadDump(fileName string, languages []string) (*Dump, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var r io.Reader = file
+	switch {
+	case strings.HasSuffix(fileName, ".gz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		r = gz
+	case strings.HasSuffix(fileName, ".bz2"):
+		r = bzip2.NewReader(file)
+	}
+
+	wanted := map[string]bool{}
+	for _, locale := range languages {
+		wanted[locale] = true
+	}
+
+	dump := &Dump{entities: map[string]*Entity{}}
+	reader := bufio.NewReaderSize(r, 1024*1024)
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 1 {
+			if err := dump.add(line, wanted); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", fileName, n, err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("Wikidata dump: %d items\n", len(dump.entities))
+	return dump, nil
+}
+
+func (d *Dump) add(line []byte, wanted map[string]bool) error {
+	line = []byte(strings.TrimRight(strings.TrimSpace(string(line)), ","))
+	var item dumpEntity
+	if err := json.Unmarshal(line, &item); err != nil {
+		return err
+	}
+
+	entity := &Entity{Labels: map[string]string{}, Aliases: map[string][]string{}}
+	for lang, label := range item.Labels {
+		if locale := locales.Canonical(lang); wanted[locale] {
+			entity.Labels[locale] = label.Value
+		}
+	}
+	for lang, aliases := range item.Aliases {
+		locale := locales.Canonical(lang)
+		if !wanted[locale] {
+			continue
+		}
+		for _, alias := range aliases {
+			entity.Aliases[locale] = append(entity.Aliases[locale], alias.Value)
+		}
+	}
+	if len(entity.Labels) != 0 || len(entity.Aliases) != 0 {
+		d.entities[item.ID] = entity
+	}
+	return nil
+}
+
+func (d *Dump) Entity(_ context.Context, id string) (*Entity, error) {
+	entity, ok := d.entities[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s isn't in the dump", models.ErrEmptyResult, id)
+	}
+	return entity, nil
+}
@@ -0,0 +1,91 @@
+package wikidata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/locales"
+)
+
+const (
+	rdfsLabel    = "http://www.w3.org/2000/01/rdf-schema#label"
+	skosAltLabel = "http://www.w3.org/2004/02/skos/core#altLabel"
+)
+
+// SPARQL reads labels and aliases from a Wikidata Query Service endpoint.
+type SPARQL struct {
+	Client    *httpclient.Client
+	URL       string
+	Languages []string
+}
+
+var _ Source = (*SPARQL)(nil)
+
+type sparqlResponse struct {
+	Results struct {
+		Bindings []struct {
+			Property struct {
+				Value string `json:"value"`
+			} `json:"property"`
+			Text struct {
+				Lang  string `json:"xml:lang"`
+				Value string `json:"value"`
+			} `json:"text"`
+		} `json:"bindings"`
+	} `json:"results"`
+}
+
+// query selects the labels and aliases of the item id in the wanted
+// languages; id has been validated as a Q-ID.
+func (s *SPARQL) query(id string) string {
+	tags := make([]string, len(s.Languages))
+	for i, locale := range s.Languages {
+		tags[i] = fmt.Sprintf("%q", strings.ToLower(locale))
+	}
+	return fmt.Sprintf(`SELECT ?property ?text WHERE {
+  VALUES ?property { rdfs:label skos:altLabel }
+  wd:%s ?property ?text .
+  FILTER(LANG(?text) IN (%s))
+}`, id, strings.Join(tags, ", "))
+}
+
+func (s *SPARQL) Entity(ctx context.Context, id string) (*Entity, error) {
+	params := url.Values{}
+	params.Add("query", s.query(id))
+	params.Add("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/sparql-results+json")
+	// the Wikidata Query Service rejects requests without a descriptive agent
+	req.Header.Add("User-Agent", "geocapture (https://github.com/lensgolda/geocapture)")
+
+	body, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var data sparqlResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+
+	entity := &Entity{Labels: map[string]string{}, Aliases: map[string][]string{}}
+	for _, b := range data.Results.Bindings {
+		locale := locales.Canonical(b.Text.Lang)
+		switch b.Property.Value {
+		case rdfsLabel:
+			entity.Labels[locale] = b.Text.Value
+		case skosAltLabel:
+			entity.Aliases[locale] = append(entity.Aliases[locale], b.Text.Value)
+		}
+	}
+	return entity, nil
+}
@@ -0,0 +1,135 @@
+package wikidata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lensgolda/geocapture/httpclient"
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/locales"
+	"github.com/lensgolda/geocapture/models"
+	"github.com/lensgolda/geocapture/providers"
+	"github.com/lensgolda/geocapture/ratelimit"
+	"github.com/lensgolda/geocapture/settings"
+)
+
+const (
+	providerName       = "wikidata"
+	providerFailedFile = "wikidata.failed"
+)
+
+var itemID = regexp.MustCompile(`^Q[1-9][0-9]*$`)
+
+// Entity holds the labels and aliases of a Wikidata item by canonical
+// locale.
+type Entity struct {
+	Labels  map[string]string
+	Aliases map[string][]string
+}
+
+// Source resolves a Q-ID to the labels and aliases of the item.
+type Source interface {
+	Entity(ctx context.Context, id string) (*Entity, error)
+}
+
+// Wikidata takes the labels of the Wikidata item a record is linked to,
+// either stored with its geo attributes or found by a provider earlier in a
+// chain.
+type Wikidata struct {
+	Name           string
+	FailedFileName string
+	Source         Source
+	// AllNames keeps the aliases as alternatives.
+	AllNames bool
+}
+
+var _ interfaces.Provider = (*Wikidata)(nil)
+
+func init() {
+	providers.Register(providers.Registration{
+		Name: providerName,
+		New: func() (interfaces.Provider, error) {
+			return NewProvider()
+		},
+	})
+}
+
+func NewProvider() (*Wikidata, error) {
+	list := settings.Config.Wikidata.Languages
+	if len(list) == 0 {
+		list = settings.Config.App.Locales
+	}
+	languages, err := locales.Parse(list)
+	if err != nil {
+		return nil, fmt.Errorf("WIKIDATA_LANGUAGES: %w", err)
+	}
+	if len(languages) == 0 {
+		return nil, errors.New("WIKIDATA_LANGUAGES is empty")
+	}
+
+	var source Source
+	if settings.Config.Wikidata.DumpFile != "" {
+		source, err = LoadDump(settings.Config.Wikidata.DumpFile, languages)
+	} else {
+		var client *httpclient.Client
+		client, err = httpclient.New(
+			60*time.Second,
+			ratelimit.New(settings.Config.Wikidata.Rate, settings.Config.Wikidata.Burst),
+			settings.Config.Wikidata.Hosts,
+		)
+		source = &SPARQL{Client: client, URL: settings.Config.Wikidata.SPARQLURL, Languages: languages}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wikidata{
+		Name:           providerName,
+		FailedFileName: providerFailedFile,
+		Source:         source,
+		AllNames:       settings.Config.App.AllNames,
+	}, nil
+}
+
+func (wd *Wikidata) Lookup(ctx context.Context, model models.Model) (*models.Result, error) {
+	id := models.WikidataOf(model)
+	if id == "" {
+		return nil, models.ErrNotLinked
+	}
+	if !itemID.MatchString(id) {
+		return nil, fmt.Errorf("%w: invalid Q-ID %q", models.ErrNotLinked, id)
+	}
+
+	entity, err := wd.Source.Entity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.NewResult(wd.Name)
+	for locale, label := range entity.Labels {
+		result.Names[locale] = label
+	}
+	if wd.AllNames {
+		for locale, aliases := range entity.Aliases {
+			for _, alias := range aliases {
+				result.AddAlternative(locale, models.Alternative{Name: alias, Kind: models.KindAlt, Providers: []string{wd.Name}})
+			}
+		}
+	}
+
+	if len(result.Names) == 0 {
+		return nil, models.ErrNoLocale
+	}
+	return result, nil
+}
+
+func (wd *Wikidata) ProviderName() string {
+	return wd.Name
+}
+
+func (wd *Wikidata) FailedFile() string {
+	return wd.FailedFileName
+}
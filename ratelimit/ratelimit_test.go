@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBurst(t *testing.T) {
+	l := New(1, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("Wait() %d within burst: %v", i, err)
+		}
+	}
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait() past burst = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRefill(t *testing.T) {
+	l := New(50, 1)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first token is in the bucket, the other three take 20ms each
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("4 tokens at 50/s with burst 1 took %s, want about 60ms", elapsed)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		l := New(rate, 1)
+		for i := 0; i < 1000; i++ {
+			if err := l.Wait(context.Background()); err != nil {
+				t.Fatalf("rate %v: Wait() = %v", rate, err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := New(0, 1).Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait() with a canceled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCanceledWhileWaiting(t *testing.T) {
+	l := New(0.1, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait() = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait() returned %s after the cancellation", elapsed)
+	}
+}
@@ -1,9 +1,20 @@
 package settings
 
 import (
+	"time"
+
 	"github.com/caarlos0/env"
 )
 
+type App struct {
+	Providers      []string `env:"GEOCAPTURE_PROVIDER" envDefault:"nominatim"`
+	Locales        []string `env:"GEOCAPTURE_LOCALES" envDefault:"ru,en,kk,uk"`
+	CheckpointFile string   `env:"GEOCAPTURE_CHECKPOINT_FILE" envDefault:"geocapture.checkpoint"`
+	Workers        int      `env:"GEOCAPTURE_WORKERS" envDefault:"1"`
+	AllNames       bool     `env:"GEOCAPTURE_ALL_NAMES"`
+	Reverse        bool     `env:"GEOCAPTURE_REVERSE"`
+}
+
 type DB struct {
 	Host string `env:"DB_HOST" envDefault:"localhost"`
 	Port string `env:"DB_PORT"`
@@ -11,27 +22,126 @@ type DB struct {
 	SSL  string `env:"DB_SSL_MODE" envDefault:"disable"`
 }
 
+type HTTP struct {
+	MaxRetries int           `env:"HTTP_MAX_RETRIES" envDefault:"3"`
+	BaseDelay  time.Duration `env:"HTTP_BACKOFF_BASE" envDefault:"1s"`
+	MaxDelay   time.Duration `env:"HTTP_BACKOFF_MAX" envDefault:"30s"`
+	// FailoverCooldown is how long a failing endpoint is avoided.
+	FailoverCooldown time.Duration `env:"HTTP_FAILOVER_COOLDOWN" envDefault:"1m"`
+}
+
+type Breaker struct {
+	Threshold int           `env:"BREAKER_THRESHOLD" envDefault:"5"`
+	Cooldown  time.Duration `env:"BREAKER_COOLDOWN" envDefault:"30s"`
+}
+
+type Nominatim struct {
+	Hosts []string `env:"NOMINATIM_HOSTS" envDefault:"https://nominatim.openstreetmap.org"`
+	Rate  float64  `env:"NOMINATIM_RATE" envDefault:"1"`
+	Burst int      `env:"NOMINATIM_BURST" envDefault:"1"`
+}
+
 type Algolia struct {
-	AppId  string `env:"ALGOLIA_APP_ID,required"`
-	ApiKey string `env:"ALGOLIA_API_KEY,required"`
-	URL    string `env:"ALGOLIA_API_URL" envDefault:"https://places-dsn.algolia.net/1/places/query"`
+	AppId  string   `env:"ALGOLIA_APP_ID"`
+	ApiKey string   `env:"ALGOLIA_API_KEY"`
+	URL    string   `env:"ALGOLIA_API_URL" envDefault:"https://places-dsn.algolia.net/1/places/query"`
+	Hosts  []string `env:"ALGOLIA_HOSTS" envDefault:"https://places-dsn.algolia.net,https://places-1.algolianet.com,https://places-2.algolianet.com,https://places-3.algolianet.com"`
+	Rate   float64  `env:"ALGOLIA_RATE" envDefault:"20"`
+	Burst  int      `env:"ALGOLIA_BURST" envDefault:"5"`
 }
 
 type Mapquest struct {
-	ApiKey string `env:"MAPQUEST_API_KEY,required"`
-	URL    string `env:"MAPQUEST_API_URL" envDefault:"http://open.mapquestapi.com/nominatim/v1/search.php?"`
+	ApiKey     string   `env:"MAPQUEST_API_KEY"`
+	URL        string   `env:"MAPQUEST_API_URL" envDefault:"http://open.mapquestapi.com/nominatim/v1/search.php?"`
+	ReverseURL string   `env:"MAPQUEST_REVERSE_URL" envDefault:"http://open.mapquestapi.com/nominatim/v1/reverse.php?"`
+	Hosts      []string `env:"MAPQUEST_HOSTS"`
+	Rate       float64  `env:"MAPQUEST_RATE" envDefault:"3"`
+	Burst      int      `env:"MAPQUEST_BURST" envDefault:"1"`
+}
+
+// Photon points at komoot's public instance by default; set PHOTON_URL to
+// use a self-hosted one. Languages are the lang values to ask for and
+// default to GEOCAPTURE_LOCALES; the public instance only knows en, de, fr
+// and it.
+type Photon struct {
+	URL       string   `env:"PHOTON_URL" envDefault:"https://photon.komoot.io/api/?"`
+	Hosts     []string `env:"PHOTON_HOSTS"`
+	Languages []string `env:"PHOTON_LANGUAGES"`
+	Rate      float64  `env:"PHOTON_RATE" envDefault:"10"`
+	Burst     int      `env:"PHOTON_BURST" envDefault:"2"`
+}
+
+// Pelias points at our own instance, e.g. http://pelias:4000. Languages
+// default to GEOCAPTURE_LOCALES.
+type Pelias struct {
+	URL       string   `env:"PELIAS_URL"`
+	ApiKey    string   `env:"PELIAS_API_KEY"`
+	Hosts     []string `env:"PELIAS_HOSTS"`
+	Languages []string `env:"PELIAS_LANGUAGES"`
+	Rate      float64  `env:"PELIAS_RATE" envDefault:"25"`
+	Burst     int      `env:"PELIAS_BURST" envDefault:"5"`
+}
+
+// OpenCage languages default to GEOCAPTURE_LOCALES.
+type OpenCage struct {
+	ApiKey    string   `env:"OPENCAGE_API_KEY"`
+	URL       string   `env:"OPENCAGE_API_URL" envDefault:"https://api.opencagedata.com/geocode/v1/json?"`
+	Hosts     []string `env:"OPENCAGE_HOSTS"`
+	Languages []string `env:"OPENCAGE_LANGUAGES"`
+	Rate      float64  `env:"OPENCAGE_RATE" envDefault:"1"`
+	Burst     int      `env:"OPENCAGE_BURST" envDefault:"1"`
+}
+
+// Geonames points at local dumps from https://download.geonames.org/export/dump/;
+// PlacesFile may be allCountries.txt or a smaller citiesNNN.txt. Only
+// alternate names in Languages, GEOCAPTURE_LOCALES by default, are kept.
+type Geonames struct {
+	PlacesFile         string   `env:"GEONAMES_PLACES_FILE"`
+	AlternateNamesFile string   `env:"GEONAMES_ALTERNATE_NAMES_FILE"`
+	Languages          []string `env:"GEONAMES_LANGUAGES"`
+	MinPopulation      int64    `env:"GEONAMES_MIN_POPULATION" envDefault:"500"`
+}
+
+// Wikidata reads labels from the local JSON dump in DumpFile when it is set
+// and from the SPARQL endpoint otherwise. Languages default to
+// GEOCAPTURE_LOCALES, or -locales when it is given.
+type Wikidata struct {
+	SPARQLURL string   `env:"WIKIDATA_SPARQL_URL" envDefault:"https://query.wikidata.org/sparql?"`
+	DumpFile  string   `env:"WIKIDATA_DUMP_FILE"`
+	Hosts     []string `env:"WIKIDATA_HOSTS"`
+	Languages []string `env:"WIKIDATA_LANGUAGES"`
+	Rate      float64  `env:"WIKIDATA_RATE" envDefault:"5"`
+	Burst     int      `env:"WIKIDATA_BURST" envDefault:"1"`
 }
 
 type AppConfig struct {
-	Algolia  *Algolia
-	Mapquest *Mapquest
-	DB       *DB
+	App       *App
+	HTTP      *HTTP
+	Breaker   *Breaker
+	Nominatim *Nominatim
+	Algolia   *Algolia
+	Mapquest  *Mapquest
+	Photon    *Photon
+	Pelias    *Pelias
+	OpenCage  *OpenCage
+	Geonames  *Geonames
+	Wikidata  *Wikidata
+	DB        *DB
 }
 
 var Config = &AppConfig{
-	Algolia:  &Algolia{},
-	Mapquest: &Mapquest{},
-	DB:       &DB{},
+	App:       &App{},
+	HTTP:      &HTTP{},
+	Breaker:   &Breaker{},
+	Nominatim: &Nominatim{},
+	Algolia:   &Algolia{},
+	Mapquest:  &Mapquest{},
+	Photon:    &Photon{},
+	Pelias:    &Pelias{},
+	OpenCage:  &OpenCage{},
+	Geonames:  &Geonames{},
+	Wikidata:  &Wikidata{},
+	DB:        &DB{},
 }
 
 func LoadSettings() error {
@@ -40,14 +150,41 @@ func LoadSettings() error {
 	if err = env.Parse(Config); err != nil {
 		return err
 	}
+	if err = env.Parse(Config.App); err != nil {
+		return err
+	}
 	if err = env.Parse(Config.DB); err != nil {
 		return err
 	}
+	if err = env.Parse(Config.HTTP); err != nil {
+		return err
+	}
+	if err = env.Parse(Config.Breaker); err != nil {
+		return err
+	}
+	if err = env.Parse(Config.Nominatim); err != nil {
+		return err
+	}
 	if err = env.Parse(Config.Algolia); err != nil {
 		return err
 	}
 	if err = env.Parse(Config.Mapquest); err != nil {
 		return err
 	}
+	if err = env.Parse(Config.Photon); err != nil {
+		return err
+	}
+	if err = env.Parse(Config.Pelias); err != nil {
+		return err
+	}
+	if err = env.Parse(Config.OpenCage); err != nil {
+		return err
+	}
+	if err = env.Parse(Config.Geonames); err != nil {
+		return err
+	}
+	if err = env.Parse(Config.Wikidata); err != nil {
+		return err
+	}
 	return nil
 }
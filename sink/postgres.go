@@ -0,0 +1,185 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/models"
+)
+
+// statements of an entity. The delete* ones remove what an earlier run
+// stored for the same record, so that storing it again replaces its rows.
+type statements struct {
+	deleteTranslation  string
+	translation        string
+	deleteAlternatives string
+	alternative        string
+	geo                string
+}
+
+var (
+	cityStatements = statements{
+		deleteTranslation:  "DELETE FROM cities_translations WHERE city_id = $1 AND locale = $2 AND provider = $3",
+		translation:        "INSERT INTO cities_translations(city_id, locale, name, int_name, provider, confidence) VALUES ($1, $2, $3, $4, $5, $6)",
+		deleteAlternatives: "DELETE FROM cities_translations_alternatives WHERE city_id = $1 AND locale = $2 AND provider = $3",
+		alternative:        "INSERT INTO cities_translations_alternatives(city_id, locale, name, kind, providers, confidence, rank, provider) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		geo:                "INSERT INTO cities_geo_attributes(city_id, " + geoColumns + ") VALUES (" + geoValues + ") ON CONFLICT (city_id, provider) DO UPDATE SET " + geoUpdate,
+	}
+	countryStatements = statements{
+		deleteTranslation:  "DELETE FROM countries_translations_temp WHERE country_id = $1 AND locale = $2 AND provider = $3",
+		translation:        "INSERT INTO countries_translations_temp(country_id, locale, name, int_name, provider, confidence) VALUES ($1, $2, $3, $4, $5, $6)",
+		deleteAlternatives: "DELETE FROM countries_translations_alternatives WHERE country_id = $1 AND locale = $2 AND provider = $3",
+		alternative:        "INSERT INTO countries_translations_alternatives(country_id, locale, name, kind, providers, confidence, rank, provider) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		geo:                "INSERT INTO countries_geo_attributes(country_id, " + geoColumns + ") VALUES (" + geoValues + ") ON CONFLICT (country_id, provider) DO UPDATE SET " + geoUpdate,
+	}
+)
+
+const (
+	geoColumns = "provider, lat, lon, osm_type, osm_id, place_rank, bbox_south, bbox_north, bbox_west, bbox_east, wikidata"
+	geoValues  = "$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12"
+	geoUpdate  = "lat = EXCLUDED.lat, lon = EXCLUDED.lon, osm_type = EXCLUDED.osm_type, osm_id = EXCLUDED.osm_id, " +
+		"place_rank = EXCLUDED.place_rank, bbox_south = EXCLUDED.bbox_south, bbox_north = EXCLUDED.bbox_north, " +
+		"bbox_west = EXCLUDED.bbox_west, bbox_east = EXCLUDED.bbox_east, wikidata = EXCLUDED.wikidata"
+)
+
+// Postgres writes translations into the cities_translations and
+// countries_translations_temp tables together with the provider of each name,
+// alternative names into the *_translations_alternatives tables and the position
+// and OSM object of the place into cities_geo_attributes and
+// countries_geo_attributes, one row per record and provider. Storing a
+// record again, as -refresh and -resume do, replaces its translation and
+// alternatives per locale and provider.
+type Postgres struct {
+	DB *sql.DB
+}
+
+var _ interfaces.Sink = (*Postgres)(nil)
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{DB: db}
+}
+
+func (p *Postgres) Store(ctx context.Context, model models.Model, result *models.Result) error {
+	var stmts statements
+	switch model.(type) {
+	case models.City:
+		stmts = cityStatements
+	case models.Country:
+		stmts = countryStatements
+	default:
+		return models.ErrWrongModel
+	}
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	del, err := tx.PrepareContext(ctx, stmts.deleteTranslation)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = del.Close()
+	}()
+	stmt, err := tx.PrepareContext(ctx, stmts.translation)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for locale, name := range result.Names {
+		provider := result.Source(locale)
+		confidence := nullFloat(result.Confidence, locale)
+		if _, err := del.ExecContext(ctx, model.Id(), locale, provider); err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, model.Id(), locale, name, result.IntName, provider, confidence); err != nil {
+			return err
+		}
+		log.Printf("Insert OK: %sID = %d, locale = %s, name = %s, int_name = %v, provider = %s\n", model.Type(), model.Id(), locale, name, result.IntName, provider)
+	}
+
+	if err := p.storeAlternatives(ctx, tx, stmts, model, result); err != nil {
+		return err
+	}
+	if err := p.storeGeo(ctx, tx, stmts.geo, model, result.Geo); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *Postgres) storeAlternatives(ctx context.Context, tx *sql.Tx, stmts statements, model models.Model, result *models.Result) error {
+	if len(result.Alternatives) == 0 {
+		return nil
+	}
+
+	del, err := tx.PrepareContext(ctx, stmts.deleteAlternatives)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = del.Close()
+	}()
+	stmt, err := tx.PrepareContext(ctx, stmts.alternative)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for locale, alternatives := range result.Alternatives {
+		if _, ok := result.Names[locale]; !ok || len(alternatives) == 0 {
+			continue
+		}
+		provider := result.Source(locale)
+		if _, err := del.ExecContext(ctx, model.Id(), locale, provider); err != nil {
+			return err
+		}
+		for rank, alt := range alternatives {
+			kind := alt.Kind
+			if kind == "" {
+				kind = models.KindName
+			}
+			confidence := sql.NullFloat64{Float64: alt.Confidence, Valid: alt.Confidence > 0}
+			if _, err := stmt.ExecContext(ctx, model.Id(), locale, alt.Name, kind, strings.Join(alt.Providers, ","), confidence, rank+1, provider); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Postgres) storeGeo(ctx context.Context, tx *sql.Tx, query string, model models.Model, geo *models.Geo) error {
+	if geo == nil {
+		return nil
+	}
+
+	var bbox [4]sql.NullFloat64
+	if len(geo.BoundingBox) == 4 {
+		for i, f := range geo.BoundingBox {
+			bbox[i] = sql.NullFloat64{Float64: f, Valid: true}
+		}
+	}
+	_, err := tx.ExecContext(ctx, query, model.Id(), geo.Provider, geo.Lat, geo.Lon,
+		sql.NullString{String: geo.OSMType, Valid: geo.OSMType != ""},
+		sql.NullInt64{Int64: geo.OSMID, Valid: geo.OSMID != 0},
+		sql.NullInt64{Int64: int64(geo.PlaceRank), Valid: geo.PlaceRank != 0},
+		bbox[0], bbox[1], bbox[2], bbox[3],
+		sql.NullString{String: geo.Wikidata, Valid: geo.Wikidata != ""})
+	return err
+}
+
+func nullFloat(values map[string]float64, key string) sql.NullFloat64 {
+	value, ok := values[key]
+	return sql.NullFloat64{Float64: value, Valid: ok}
+}
@@ -0,0 +1,55 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/lensgolda/geocapture/interfaces"
+	"github.com/lensgolda/geocapture/models"
+)
+
+// Writer emits every result as a JSON line, useful for dry runs and for
+// tools that don't need a database.
+type Writer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+type record struct {
+	Type     string            `json:"type"`
+	ID       int               `json:"id"`
+	Provider string            `json:"provider"`
+	Names    map[string]string `json:"names"`
+	IntName  *string           `json:"int_name,omitempty"`
+	Sources  map[string]string `json:"sources,omitempty"`
+
+	Confidence   map[string]float64              `json:"confidence,omitempty"`
+	Alternatives map[string][]models.Alternative `json:"alternatives,omitempty"`
+	Geo          *models.Geo                     `json:"geo,omitempty"`
+}
+
+var _ interfaces.Sink = (*Writer)(nil)
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+func (w *Writer) Store(_ context.Context, model models.Model, result *models.Result) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.enc.Encode(record{
+		Type:     model.Type(),
+		ID:       model.Id(),
+		Provider: result.Provider,
+		Names:    result.Names,
+		IntName:  result.IntName,
+		Sources:  result.Sources,
+
+		Confidence:   result.Confidence,
+		Alternatives: result.Alternatives,
+		Geo:          result.Geo,
+	})
+}